@@ -0,0 +1,163 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/isacikgoz/gitin/git"
+	"github.com/isacikgoz/gitin/prompt"
+	"github.com/isacikgoz/gitin/term"
+	"github.com/justincampbell/timeago"
+)
+
+// blame holds the repository struct and the prompt pointer.
+type blame struct {
+	repository *git.Repository
+	prompt     *prompt.Prompt
+}
+
+// blameLine is a line of the file with the commit that last changed it
+type blameLine struct {
+	Hash    string
+	Author  string
+	Email   string
+	When    time.Time
+	Summary string
+	Number  int
+	Content string
+}
+
+func (l *blameLine) String() string {
+	return l.Content
+}
+
+// committed returns false if the line is changed in the working tree
+func (l *blameLine) committed() bool {
+	return strings.Trim(l.Hash, "0") != ""
+}
+
+// BlamePrompt configures a prompt to serve as a blame prompt of the file
+func BlamePrompt(r *git.Repository, path string, opts *Options) (*prompt.Prompt, error) {
+	lines, err := loadBlame(r, path)
+	if err != nil {
+		return nil, fmt.Errorf("could not blame %s: %v", path, err)
+	}
+	list, err := prompt.NewAsyncList(lines, lineSize(opts))
+	if err != nil {
+		return nil, fmt.Errorf("could not create list: %v", err)
+	}
+
+	b := &blame{repository: r}
+	b.prompt = prompt.Create("Blame of "+path, &opts.Options, list,
+		prompt.WithSelectionHandler(b.onSelect),
+		prompt.WithItemRenderer(itemRenderer(opts)),
+		prompt.WithEmptyMessage(noMatches("lines")),
+		prompt.WithInformation(b.info),
+	)
+	if err := b.prompt.AddKeyBinding(copyBinding(b.prompt)); err != nil {
+		return nil, err
+	}
+	return b.prompt, nil
+}
+
+// loadBlame streams the lines of the file as git annotates them so that the
+// first page is displayed without waiting for the large files
+func loadBlame(r *git.Repository, path string) (chan interface{}, error) {
+	// the path is relative to the working directory rather than the repository
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	cmd := gitCommand(r, "blame", "--line-porcelain", "--", path)
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	lines := make(chan interface{})
+	go func() {
+		defer close(lines)
+		defer cmd.Wait()
+		scanner := bufio.NewScanner(out)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		line := &blameLine{}
+		for scanner.Scan() {
+			text := scanner.Text()
+			// the content is prefixed with a tab, the headers are not
+			if strings.HasPrefix(text, "\t") {
+				line.Content = expandTabs(text[1:])
+				lines <- line
+				line = &blameLine{}
+				continue
+			}
+			key, value := text, ""
+			if i := strings.IndexByte(text, ' '); i > 0 {
+				key, value = text[:i], text[i+1:]
+			}
+			switch key {
+			case "author":
+				line.Author = value
+			case "author-mail":
+				line.Email = strings.Trim(value, "<>")
+			case "author-time":
+				if sec, err := strconv.ParseInt(value, 10, 64); err == nil {
+					line.When = time.Unix(sec, 0)
+				}
+			case "summary":
+				line.Summary = value
+			default:
+				// "<hash> <original line> <final line> [<lines in group>]"
+				if fields := strings.Fields(text); len(key) == 40 && len(fields) >= 3 {
+					line.Hash = key
+					line.Number, _ = strconv.Atoi(fields[2])
+				}
+			}
+		}
+	}()
+	return lines, nil
+}
+
+func (b *blame) onSelect(item interface{}) error {
+	line := item.(*blameLine)
+	if !line.committed() {
+		return nil
+	}
+	return popGitCommand(b.repository, []string{"show", line.Hash})
+}
+
+func (b *blame) info(item interface{}) [][]term.Cell {
+	line := item.(*blameLine)
+	grid := make([][]term.Cell, 0)
+	if !line.committed() {
+		return append(grid, term.Cprint("Not committed yet", color.Faint))
+	}
+	cells := term.Cprint(line.Hash[:7]+" ", color.FgCyan)
+	cells = append(cells, term.Cprint(line.Summary, color.FgWhite)...)
+	grid = append(grid, cells)
+	cells = term.Cprint("Author ", color.Faint)
+	cells = append(cells, term.Cprint(line.Author+" <"+line.Email+">", color.FgYellow)...)
+	cells = append(cells, term.Cprint(" "+timeago.FromTime(line.When), color.FgBlue)...)
+	return append(grid, cells)
+}
+
+// blameGutter renders the fixed width metadata of the line
+func blameGutter(l *blameLine) []term.Cell {
+	hash, author, date := "0000000", "Not Committed Yet", ""
+	if l.committed() {
+		hash, author, date = l.Hash[:7], l.Author, l.When.Format("2006-01-02")
+	}
+	cells := term.Cprint(hash+" ", color.FgCyan)
+	cells = append(cells, term.Cprint(fmt.Sprintf("%-12.12s %10s ", author, date), color.Faint)...)
+	return append(cells, term.Cprint(fmt.Sprintf("%4d ", l.Number), color.FgYellow)...)
+}
@@ -2,12 +2,12 @@ package cli
 
 import (
 	"fmt"
-	"os/exec"
+	"strings"
 
 	"github.com/fatih/color"
+	"github.com/isacikgoz/gitin/git"
 	"github.com/isacikgoz/gitin/prompt"
 	"github.com/isacikgoz/gitin/term"
-	"github.com/isacikgoz/gitin/git"
 	"github.com/justincampbell/timeago"
 )
 
@@ -15,26 +15,34 @@ import (
 type branch struct {
 	repository *git.Repository
 	prompt     *prompt.Prompt
+	remotes    bool // list the remote branches as well
+	dry        dryRun
+
+	output commandOutput // of the running or the last remote command
 }
 
 // BranchPrompt configures a prompt to serve as a branch prompt
-func BranchPrompt(r *git.Repository, opts *prompt.Options) (*prompt.Prompt, error) {
-	branches, err := r.Branches()
+func BranchPrompt(r *git.Repository, opts *Options) (*prompt.Prompt, error) {
+	b := &branch{repository: r, dry: dryRun{enabled: opts.DryRun}}
+	branches, err := b.loadBranches()
 	if err != nil {
 		return nil, fmt.Errorf("could not load branches: %v", err)
 	}
-	list, err := prompt.NewList(branches, opts.LineSize)
+	list, err := prompt.NewList(branches, lineSize(opts))
 	if err != nil {
 		return nil, fmt.Errorf("could not create list: %v", err)
 	}
 
-	b := &branch{repository: r}
-	b.prompt = prompt.Create("Branches", opts, list,
+	b.prompt = prompt.Create("Branches", &opts.Options, list,
 		prompt.WithSelectionHandler(b.onSelect),
-		prompt.WithItemRenderer(renderItem),
+		prompt.WithItemRenderer(itemRenderer(opts)),
+		prompt.WithEmptyMessage(noMatches("branches")),
 		prompt.WithInformation(b.branchInfo),
 	)
-	b.defineKeyBindings()
+	b.output.prompt = b.prompt
+	if err := b.defineKeyBindings(opts); err != nil {
+		return nil, err
+	}
 
 	return b.prompt, nil
 }
@@ -42,8 +50,10 @@ func BranchPrompt(r *git.Repository, opts *prompt.Options) (*prompt.Prompt, erro
 func (b *branch) onSelect(item interface{}) error {
 	branch := item.(*git.Branch)
 	args := []string{"checkout", branch.Name}
-	cmd := exec.Command("git", args...)
-	cmd.Dir = b.repository.Path()
+	if b.dry.skip(b.prompt, args) {
+		return nil
+	}
+	cmd := gitCommand(b.repository, args...)
 	if err := cmd.Run(); err != nil {
 		return nil // possibly dirty branch
 	}
@@ -51,14 +61,9 @@ func (b *branch) onSelect(item interface{}) error {
 	return nil
 }
 
-func (b *branch) defineKeyBindings() error {
+func (b *branch) defineKeyBindings(opts *Options) error {
 	keybindings := []*prompt.KeyBinding{
-		&prompt.KeyBinding{
-			Key:     'd',
-			Display: "d",
-			Desc:    "delete branch",
-			Handler: b.deleteBranch,
-		},
+		deleteBinding(opts, "delete branch", b.deleteBranch),
 		&prompt.KeyBinding{
 			Key:     'D',
 			Display: "D",
@@ -66,11 +71,56 @@ func (b *branch) defineKeyBindings() error {
 			Handler: b.forceDeleteBranch,
 		},
 		&prompt.KeyBinding{
-			Key:     'q',
-			Display: "q",
-			Desc:    "quit",
-			Handler: b.quit,
+			Key:     'n',
+			Display: "n",
+			Desc:    "new branch",
+			Primary: true,
+			Handler: b.newBranch,
+		},
+		&prompt.KeyBinding{
+			Key:     'R',
+			Display: "R",
+			Desc:    "rename branch",
+			Handler: b.renameBranch,
+		},
+		&prompt.KeyBinding{
+			Key:     'r',
+			Display: "r",
+			Desc:    "toggle remote branches",
+			Handler: b.toggleRemotes,
 		},
+		&prompt.KeyBinding{
+			Key:     'f',
+			Display: "f",
+			Desc:    "fetch remote",
+			Handler: b.fetch,
+		},
+		&prompt.KeyBinding{
+			Key:     'u',
+			Display: "u",
+			Desc:    "pull branch",
+			Handler: b.pull,
+		},
+		&prompt.KeyBinding{
+			Key:     'P',
+			Display: "P",
+			Desc:    "push branch",
+			Primary: true,
+			Handler: b.push,
+		},
+		&prompt.KeyBinding{
+			Key:     '[',
+			Display: "[",
+			Desc:    "scroll output back",
+			Handler: b.scrollOutput,
+		},
+		&prompt.KeyBinding{
+			Key:     ']',
+			Display: "]",
+			Desc:    "scroll output forward",
+			Handler: b.unscrollOutput,
+		},
+		copyBinding(b.prompt),
 	}
 	for _, kb := range keybindings {
 		if err := b.prompt.AddKeyBinding(kb); err != nil {
@@ -93,7 +143,8 @@ func (b *branch) branchInfo(item interface{}) [][]term.Cell {
 		}
 		grid = append(grid, branchInfo(branch, false)...)
 	}
-	return grid
+	grid = append(grid, b.output.cells()...)
+	return append(grid, b.dry.info()...)
 }
 
 func (b *branch) deleteBranch(item interface{}) error {
@@ -106,22 +157,176 @@ func (b *branch) forceDeleteBranch(item interface{}) error {
 
 func (b *branch) bareDelete(item interface{}, mode string) error {
 	branch := item.(*git.Branch)
-	cmd := exec.Command("git", "branch", "-"+mode, branch.Name)
-	cmd.Dir = b.repository.Path()
+	if ok, err := b.prompt.Confirm("Delete branch " + branch.Name + "?"); !ok {
+		return err
+	}
+	args := []string{"branch", "-" + mode, branch.Name}
+	if b.dry.skip(b.prompt, args) {
+		return nil
+	}
+	cmd := gitCommand(b.repository, args...)
 	if err := cmd.Run(); err != nil {
 		return nil // possibly an unmerged branch, just ignore it
 	}
 	return b.reloadBranches()
 }
 
-func (b *branch) quit(item interface{}) error {
-	b.prompt.Stop()
+// newBranch creates a branch starting from the selected one
+func (b *branch) newBranch(item interface{}) error {
+	branch := item.(*git.Branch)
+	name, err := b.prompt.Input("New branch from "+branch.Name, "")
+	if err != nil || len(name) == 0 {
+		return err
+	}
+	args := []string{"branch", name, branch.Name}
+	if b.dry.skip(b.prompt, args) {
+		return nil
+	}
+	cmd := gitCommand(b.repository, args...)
+	if err := cmd.Run(); err != nil {
+		return nil // possibly an invalid or existing name
+	}
+	return b.reloadBranches()
+}
+
+func (b *branch) renameBranch(item interface{}) error {
+	branch := item.(*git.Branch)
+	if branch.IsRemote() {
+		return nil
+	}
+	name, err := b.prompt.Input("Rename "+branch.Name, branch.Name)
+	if err != nil || len(name) == 0 || name == branch.Name {
+		return err
+	}
+	args := []string{"branch", "-m", branch.Name, name}
+	if b.dry.skip(b.prompt, args) {
+		return nil
+	}
+	cmd := gitCommand(b.repository, args...)
+	if err := cmd.Run(); err != nil {
+		return nil // possibly an invalid or existing name
+	}
+	return b.reloadBranches()
+}
+
+// fetch updates the remote of the branch
+func (b *branch) fetch(item interface{}) error {
+	branch := item.(*git.Branch)
+	remote, _, ok := b.upstream(branch)
+	if !ok {
+		return b.output.show(noUpstream(branch))
+	}
+	return b.runRemoteCommand([]string{"fetch", "--progress", remote})
+}
+
+// pull fast-forwards the branch to its upstream, the ones other than HEAD are
+// updated without checking them out
+func (b *branch) pull(item interface{}) error {
+	branch := item.(*git.Branch)
+	remote, merge, ok := b.upstream(branch)
+	if !ok {
+		return b.output.show(noUpstream(branch))
+	}
+	if branch.IsRemote() {
+		return b.output.show("Remote branches can only be fetched.")
+	}
+	args := []string{"fetch", "--progress", remote, merge + ":refs/heads/" + branch.Name}
+	if branch.Head {
+		args = []string{"pull", "--ff-only", "--progress"}
+	}
+	return b.runRemoteCommand(args)
+}
+
+// push updates the upstream of the branch
+func (b *branch) push(item interface{}) error {
+	branch := item.(*git.Branch)
+	remote, merge, ok := b.upstream(branch)
+	if !ok {
+		return b.output.show(noUpstream(branch))
+	}
+	if branch.IsRemote() {
+		return b.output.show("Remote branches can only be fetched.")
+	}
+	if ok, err := b.prompt.Confirm("Push " + branch.Name + " to " + remote + "?"); !ok {
+		return err
+	}
+	return b.runRemoteCommand([]string{"push", "--progress", remote, "refs/heads/" + branch.Name + ":" + merge})
+}
+
+// upstream returns the remote and the merge ref of the branch from the config.
+// A remote branch is its own upstream.
+func (b *branch) upstream(branch *git.Branch) (string, string, bool) {
+	if branch.IsRemote() {
+		parts := strings.SplitN(branch.Name, "/", 2)
+		if len(parts) < 2 {
+			return "", "", false
+		}
+		return parts[0], "refs/heads/" + parts[1], true
+	}
+	remote, err := b.repository.ConfigString("branch." + branch.Name + ".remote")
+	if err != nil || len(remote) == 0 {
+		return "", "", false
+	}
+	merge, err := b.repository.ConfigString("branch." + branch.Name + ".merge")
+	if err != nil || len(merge) == 0 {
+		return "", "", false
+	}
+	return remote, merge, true
+}
+
+func noUpstream(branch *git.Branch) string {
+	return "Branch " + branch.Name + " is not tracking a remote branch."
+}
+
+// runRemoteCommand runs the git command in the background since it can take a
+// while, its output is displayed below the branch info while it runs. The
+// branches are reloaded to update the ahead/behind counts when it finishes.
+func (b *branch) runRemoteCommand(args []string) error {
+	if b.output.running || b.dry.skip(b.prompt, args) {
+		return nil
+	}
+	b.output.run(b.repository, args, func(error) {
+		b.reloadBranches()
+	})
+	return nil
+}
+
+// scrollOutput scrolls the output of the remote command back
+func (b *branch) scrollOutput(item interface{}) error {
+	b.output.scrollBy(1)
+	return nil
+}
+
+// unscrollOutput scrolls the output of the remote command forward
+func (b *branch) unscrollOutput(item interface{}) error {
+	b.output.scrollBy(-1)
 	return nil
 }
 
+func (b *branch) toggleRemotes(item interface{}) error {
+	b.remotes = !b.remotes
+	return b.reloadBranches()
+}
+
+// loadBranches returns the local branches, and the remote ones if they are
+// toggled on
+func (b *branch) loadBranches() ([]*git.Branch, error) {
+	branches, err := b.repository.Branches()
+	if err != nil || b.remotes {
+		return branches, err
+	}
+	local := make([]*git.Branch, 0)
+	for _, branch := range branches {
+		if !branch.IsRemote() {
+			local = append(local, branch)
+		}
+	}
+	return local, nil
+}
+
 // reloads the list
 func (b *branch) reloadBranches() error {
-	branches, err := b.repository.Branches()
+	branches, err := b.loadBranches()
 	if err != nil {
 		return err
 	}
@@ -1,16 +1,216 @@
 package cli
 
 import (
+	"bufio"
+	"errors"
+	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/fatih/color"
 	"github.com/isacikgoz/gitin/git"
+	"github.com/isacikgoz/gitin/prompt"
+	"github.com/isacikgoz/gitin/term"
 )
 
+// Options are the settings of the prompts, along with the ones of the git
+// commands that the prompt package doesn't know about
+type Options struct {
+	prompt.Options
+	DryRun  bool          // the commands that change the repository are displayed instead of run
+	Watch   time.Duration // how long the changes of the working tree settle before the status is reloaded, 0 disables watching
+	Icons   bool          // the items are drawn with the icons of the Nerd Fonts
+	GitPath string        // the git executable of the commands, it is looked up in the PATH if empty
+}
+
+// GitPath is the git executable that the commands are run with, git is looked
+// up in the PATH if it is empty
+var GitPath string
+
+// gitCommand returns the command to run git with the args in the repository
+func gitCommand(r *git.Repository, args ...string) *exec.Cmd {
+	path := GitPath
+	if len(path) == 0 {
+		path = "git"
+	}
+	cmd := exec.Command(path, args...)
+	cmd.Dir = r.Path()
+	return cmd
+}
+
+// lineSize returns the size to create the lists with
+func lineSize(opts *Options) int {
+	if opts.LineSize > 0 {
+		return opts.LineSize
+	}
+	return prompt.DefaultLineSize
+}
+
+// messageTimeout is how long the messages of the commands are displayed
+const messageTimeout = 3 * time.Second
+
+// showMessage displays the message below the list for a while
+func showMessage(p *prompt.Prompt, msg string) {
+	p.SetMessage(term.Cprint(msg, color.Faint), messageTimeout)
+}
+
+// showError displays the error below the list for a while
+func showError(p *prompt.Prompt, err error) {
+	p.SetMessage(term.Cprint("error: "+err.Error(), color.FgRed), messageTimeout)
+}
+
+// showConflict tells that the command has stopped because of conflicts, it is
+// displayed until a key is pressed
+func showConflict(p *prompt.Prompt, command string) {
+	msg := "Conflicts, resolve them and run git " + command + " --continue, or --abort to cancel"
+	p.SetMessage(term.Cprint(msg, color.FgYellow), 0)
+}
+
+// inProgress returns true if the ref exists, like CHERRY_PICK_HEAD while a
+// cherry-pick has stopped
+func inProgress(r *git.Repository, ref string) bool {
+	return gitCommand(r, "rev-parse", "--quiet", "--verify", ref).Run() == nil
+}
+
+// rebaseInProgress returns true if a rebase has stopped, e.g. because of a
+// conflict
+func rebaseInProgress(r *git.Repository) bool {
+	for _, dir := range []string{"rebase-merge", "rebase-apply"} {
+		out, err := gitCommand(r, "rev-parse", "--git-path", dir).Output()
+		if err != nil {
+			continue
+		}
+		path := strings.TrimSpace(string(out))
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(r.Path(), path)
+		}
+		if _, err := os.Stat(path); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// runGitCommand runs git with the args in the repository, the error has the
+// first line of the output of git if it fails
+func runGitCommand(r *git.Repository, args []string) error {
+	cmd := gitCommand(r, args...)
+	out, err := cmd.CombinedOutput()
+	return commandError(out, err)
+}
+
+// commandError returns the first line of the output that git explains the
+// failure with, or err if there is none
+func commandError(out []byte, err error) error {
+	if err == nil {
+		return nil
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		line = strings.TrimPrefix(strings.TrimPrefix(line, "error: "), "fatal: ")
+		if line = strings.TrimSpace(line); len(line) > 0 {
+			return errors.New(line)
+		}
+	}
+	return err
+}
+
+// deleteBinding returns the key binding to delete an item, with the vim keys
+// it is the dd chord so that a single d doesn't delete anything
+func deleteBinding(opts *Options, desc string, handler func(interface{}) error) *prompt.KeyBinding {
+	kb := &prompt.KeyBinding{
+		Key:     'd',
+		Display: "d",
+		Desc:    desc,
+		Handler: handler,
+	}
+	if opts.VimKeys {
+		kb.Then = 'd'
+		kb.Display = "dd"
+	}
+	return kb
+}
+
+// copyBinding returns the key binding to copy the selected item to the
+// clipboard, see copyText
+func copyBinding(p *prompt.Prompt) *prompt.KeyBinding {
+	return &prompt.KeyBinding{
+		Key:     'y',
+		Display: "y",
+		Desc:    "copy to clipboard",
+		Handler: func(item interface{}) error {
+			text := copyText(item)
+			if len(text) == 0 {
+				return nil
+			}
+			if err := p.Copy(text); err != nil {
+				showError(p, err)
+				return nil
+			}
+			showMessage(p, "Copied "+text)
+			return nil
+		},
+	}
+}
+
+// copyText returns what is useful to paste of the item, like the path of a file
+// or the hash of a commit
+func copyText(item interface{}) string {
+	switch i := item.(type) {
+	case *git.StatusEntry:
+		return i.String()
+	case *git.DiffDelta:
+		return i.NewFile.Path
+	case *git.Commit:
+		return i.Hash
+	case *git.Branch:
+		return i.Name
+	case *git.Stash:
+		return i.Hash
+	case *git.Tag:
+		return i.Shorthand
+	case *reflogEntry:
+		return i.New
+	case *blameLine:
+		if i.committed() {
+			return i.Hash
+		}
+	}
+	return ""
+}
+
+// dryRun records the git commands instead of running them if it is enabled so
+// that the user can see what would be done
+type dryRun struct {
+	enabled bool
+	last    string
+}
+
+// skip returns true if the command shouldn't be run, the command is displayed
+// in the information of the prompt
+func (d *dryRun) skip(p *prompt.Prompt, args []string) bool {
+	if !d.enabled {
+		return false
+	}
+	d.last = "git " + strings.Join(args, " ")
+	p.InvalidateInformation()
+	return true
+}
+
+func (d *dryRun) info() [][]term.Cell {
+	if len(d.last) == 0 {
+		return nil
+	}
+	cells := term.Cprint("Dry run: ", color.Faint)
+	return [][]term.Cell{append(cells, term.Cprint(d.last, color.FgYellow)...)}
+}
+
 func popGitCommand(r *git.Repository, args []string) error {
 	os.Setenv("LESS", "-RCS")
-	cmd := exec.Command("git", args...)
-	cmd.Dir = r.Path()
+	cmd := gitCommand(r, args...)
+	cmd.Env = pagerEnv(os.Environ(), term.IsTerminal(os.Stdout.Fd()))
 
 	cmd.Stdout = os.Stdout
 	cmd.Stdin = os.Stdin
@@ -18,8 +218,82 @@ func popGitCommand(r *git.Repository, args []string) error {
 	if err := cmd.Start(); err != nil {
 		return err
 	}
+	defer term.RestoreAltScreen()
 	if err := cmd.Wait(); err != nil {
 		return err
 	}
 	return nil
 }
+
+// editFile opens the file in the editor that git uses for the messages, which
+// is looked up from GIT_EDITOR, core.editor, VISUAL and EDITOR
+func editFile(r *git.Repository, path string) error {
+	out, err := gitCommand(r, "var", "GIT_EDITOR").Output()
+	if err != nil {
+		return fmt.Errorf("could not find an editor: %v", err)
+	}
+	// the editor may have arguments, so it is run by the shell like git does
+	cmd := exec.Command("sh", "-c", strings.TrimSpace(string(out))+` "$@"`, "editor", path)
+	cmd.Dir = r.Path()
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	defer term.RestoreAltScreen()
+	return cmd.Run()
+}
+
+// pagerEnv returns the environment of the git commands that page their output.
+// git looks up the pager itself so that pager.<command> is honored over
+// core.pager, the output is printed as is if it is not a terminal.
+func pagerEnv(environ []string, tty bool) []string {
+	if tty {
+		return environ
+	}
+	return append(environ, "GIT_PAGER=cat")
+}
+
+// streamGitCommand runs the git command and calls fn with each line of its
+// output as it is written. A line that is rewritten with a carriage return,
+// e.g. the progress of a fetch, is passed again with replace set. The command
+// can't read from the terminal since the prompt owns it, so it fails instead of
+// asking for credentials.
+func streamGitCommand(r *git.Repository, args []string, fn func(line string, replace bool)) error {
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer pr.Close()
+	cmd := gitCommand(r, args...)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	cmd.Stdout = pw
+	cmd.Stderr = pw
+	if err := cmd.Start(); err != nil {
+		pw.Close()
+		return err
+	}
+	pw.Close() // the command holds its own copy
+
+	reader := bufio.NewReader(pr)
+	var line []byte
+	var replace bool
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			break
+		}
+		switch b {
+		case '\r', '\n':
+			if len(line) > 0 {
+				fn(string(line), replace)
+			}
+			line = line[:0]
+			replace = b == '\r'
+		default:
+			line = append(line, b)
+		}
+	}
+	if len(line) > 0 {
+		fn(string(line), replace)
+	}
+	return cmd.Wait()
+}
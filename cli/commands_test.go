@@ -0,0 +1,23 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPagerEnv(t *testing.T) {
+	environ := []string{"PATH=/bin", "GIT_PAGER=delta"}
+	var tests = []struct {
+		tty  bool
+		want string
+	}{
+		{true, "PATH=/bin GIT_PAGER=delta"}, // git resolves the pager itself
+		{false, "PATH=/bin GIT_PAGER=delta GIT_PAGER=cat"},
+	}
+	for _, test := range tests {
+		env := pagerEnv(append([]string(nil), environ...), test.tty)
+		if got := strings.Join(env, " "); got != test.want {
+			t.Errorf("tty: %v\n got %q, want %q", test.tty, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package cli
+
+import (
+	"os"
+	"strings"
+
+	"github.com/isacikgoz/gitin/term"
+)
+
+// EmptyError is returned by the prompts if there is nothing to list, e.g. the
+// working tree is clean. It isn't a failure, the caller may print the message
+// and exit successfully.
+type EmptyError struct {
+	Message [][]term.Cell
+}
+
+func (e *EmptyError) Error() string {
+	lines := make([]string, len(e.Message))
+	for i, line := range e.Message {
+		lines[i] = cellsString(line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Print writes the message to the stdout with its colors
+func (e *EmptyError) Print() {
+	writer := term.NewBufferedWriter(os.Stdout)
+	for _, line := range e.Message {
+		writer.WriteCells(line)
+	}
+	writer.Flush()
+}
@@ -0,0 +1,144 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/isacikgoz/gitin/term"
+	"github.com/waigani/diffparser"
+)
+
+// hunk is an item of the hunk list, the selected hunks are applied together
+type hunk struct {
+	essence  *diffparser.DiffHunk
+	lines    []*hunkLine
+	selected bool
+}
+
+// hunkLine is an item of the line list of a hunk, only the selected changes of
+// a hunk are applied
+type hunkLine struct {
+	essence  *diffparser.DiffLine
+	selected bool
+}
+
+func newHunks(file *diffparser.DiffFile) []*hunk {
+	hunks := make([]*hunk, 0, len(file.Hunks))
+	for _, h := range file.Hunks {
+		lines := make([]*hunkLine, 0, len(h.WholeRange.Lines))
+		for _, line := range h.WholeRange.Lines {
+			lines = append(lines, &hunkLine{
+				essence:  line,
+				selected: line.Mode != diffparser.UNCHANGED,
+			})
+		}
+		hunks = append(hunks, &hunk{essence: h, lines: lines})
+	}
+	return hunks
+}
+
+func (h *hunk) String() string {
+	header := fmt.Sprintf("@@ -%d,%d +%d,%d @@", h.essence.OrigRange.Start, h.essence.OrigRange.Length,
+		h.essence.NewRange.Start, h.essence.NewRange.Length)
+	if len(h.essence.HunkHeader) > 0 {
+		header += " " + h.essence.HunkHeader
+	}
+	if h.partial() {
+		header += " (partial)"
+	}
+	return header
+}
+
+// partial returns true if some of the changes are deselected
+func (h *hunk) partial() bool {
+	for _, line := range h.lines {
+		if line.changed() && !line.selected {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *hunkLine) String() string {
+	return l.prefix() + l.essence.Content
+}
+
+func (l *hunkLine) changed() bool {
+	return l.essence.Mode != diffparser.UNCHANGED
+}
+
+func (l *hunkLine) prefix() string {
+	switch l.essence.Mode {
+	case diffparser.ADDED:
+		return "+"
+	case diffparser.REMOVED:
+		return "-"
+	default:
+		return " "
+	}
+}
+
+// patch returns the hunk in the unified diff format with the deselected changes
+// left out. A deselected line that exists on the side the patch is applied to
+// is converted to context, the other one is dropped. The side is the new lines
+// if the patch is going to be reversed. The line counts of the header are
+// calculated from the remaining lines.
+func (h *hunk) patch(reverse bool) string {
+	var origLen, newLen int
+	lines := make([]string, 0, len(h.lines))
+	for _, line := range h.lines {
+		prefix := line.prefix()
+		if line.changed() && !line.selected {
+			if (line.essence.Mode == diffparser.ADDED) != reverse {
+				continue
+			}
+			prefix = " "
+		}
+		switch prefix {
+		case "+":
+			newLen++
+		case "-":
+			origLen++
+		default:
+			origLen++
+			newLen++
+		}
+		lines = append(lines, prefix+line.essence.Content)
+	}
+	header := fmt.Sprintf("@@ -%d,%d +%d,%d @@", h.essence.OrigRange.Start, origLen, h.essence.NewRange.Start, newLen)
+	return header + "\n" + strings.Join(lines, "\n")
+}
+
+// hunksPatch returns a patch of the selected hunks that can be applied with
+// "git apply", it is empty if no hunk is selected
+func hunksPatch(file *diffparser.DiffFile, hunks []*hunk, reverse bool) string {
+	var patches []string
+	for _, h := range hunks {
+		if h.selected {
+			patches = append(patches, h.patch(reverse))
+		}
+	}
+	if len(patches) == 0 {
+		return ""
+	}
+	return file.DiffHeader + "\n" + strings.Join(patches, "\n")
+}
+
+// hunkLines renders the lines of the hunk colored by their change, deselected
+// changes are rendered like the context
+func hunkLines(h *hunk) [][]term.Cell {
+	grid := make([][]term.Cell, 0, len(h.lines))
+	for _, line := range h.lines {
+		content := strings.ReplaceAll(line.String(), "\t", "    ")
+		switch {
+		case !line.selected:
+			grid = append(grid, term.Cprint(content, color.Faint))
+		case line.essence.Mode == diffparser.ADDED:
+			grid = append(grid, term.Cprint(content, color.FgGreen))
+		default:
+			grid = append(grid, term.Cprint(content, color.FgRed))
+		}
+	}
+	return grid
+}
@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/isacikgoz/gitin/git"
+	"github.com/isacikgoz/gitin/term"
+)
+
+// the glyphs are from the Nerd Fonts, they are only drawn with the Icons option
+// since the other fonts lack them
+const (
+	iconFile     = '\uf15b'
+	iconConflict = '\uf071'
+	iconCommit   = '\ue729'
+	iconBranch   = '\ue725'
+	iconRemote   = '\uf0c2'
+	iconTag      = '\uf02b'
+	iconStash    = '\uf01c'
+	iconReflog   = '\uf1da'
+)
+
+// fileIcons are the icons of the files by their extensions
+var fileIcons = map[string]rune{
+	".c":    '\ue61e',
+	".css":  '\ue749',
+	".go":   '\ue627',
+	".html": '\ue60e',
+	".java": '\ue738',
+	".js":   '\ue74e',
+	".json": '\ue60b',
+	".md":   '\ue609',
+	".py":   '\ue606',
+	".rb":   '\ue791',
+	".rs":   '\ue7a8',
+	".sh":   '\uf489',
+	".ts":   '\ue628',
+	".yaml": '\ue615',
+	".yml":  '\ue615',
+}
+
+// itemRenderer returns the renderer of the items, the icons are drawn after the
+// cursor if the Icons option is set
+func itemRenderer(opts *Options) func(interface{}, []int, bool) [][]term.Cell {
+	if !opts.Icons {
+		return renderItem
+	}
+	return func(item interface{}, matches []int, selected bool) [][]term.Cell {
+		lines := renderItem(item, matches, selected)
+		icon, ok := itemIcon(item)
+		if !ok || len(lines) == 0 || len(lines[0]) < 2 {
+			return lines
+		}
+		// the icon follows the two cells of the cursor
+		line := append([]term.Cell(nil), lines[0][:2]...)
+		line = append(line, term.Cell{Ch: icon, Attr: []color.Attribute{color.Faint}}, term.Cell{Ch: ' '})
+		lines[0] = append(line, lines[0][2:]...)
+		return lines
+	}
+}
+
+// itemIcon returns the icon of the item, false if it has none
+func itemIcon(item interface{}) (rune, bool) {
+	switch i := item.(type) {
+	case *git.StatusEntry:
+		if i.Conflicted() {
+			return iconConflict, true
+		}
+		return fileIcon(i.String()), true
+	case *git.DiffDelta:
+		return fileIcon(i.String()), true
+	case *git.Commit:
+		return iconCommit, true
+	case *git.Branch:
+		if i.IsRemote() {
+			return iconRemote, true
+		}
+		return iconBranch, true
+	case *git.Tag:
+		return iconTag, true
+	case *git.Stash:
+		return iconStash, true
+	case *reflogEntry:
+		return iconReflog, true
+	}
+	return 0, false
+}
+
+func fileIcon(path string) rune {
+	if icon, ok := fileIcons[strings.ToLower(filepath.Ext(path))]; ok {
+		return icon
+	}
+	return iconFile
+}
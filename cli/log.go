@@ -19,16 +19,52 @@ type log struct {
 	prompt     *prompt.Prompt
 	selected   *git.Commit
 	oldState   *prompt.State
+	stats      map[string][]string // diff stats of the commits by hash
+	dry        dryRun
+	rebasing   bool // a rebase has stopped to be continued or aborted
 }
 
 // LogPrompt configures a prompt to serve as a commit prompt
-func LogPrompt(r *git.Repository, opts *prompt.Options) (*prompt.Prompt, error) {
+func LogPrompt(r *git.Repository, opts *Options) (*prompt.Prompt, error) {
+	list, err := loadCommits(r, lineSize(opts))
+	if err != nil {
+		return nil, err
+	}
+
+	l := &log{repository: r, stats: make(map[string][]string), dry: dryRun{enabled: opts.DryRun}}
+	l.rebasing = rebaseInProgress(r)
+	l.prompt = prompt.Create("Commits", &opts.Options, list,
+		prompt.WithSelectionHandler(l.onSelect),
+		prompt.WithItemRenderer(itemRenderer(opts)),
+		prompt.WithEmptyMessage(noMatches("commits")),
+		prompt.WithInformation(l.logInfo),
+		prompt.WithQuitHandler(l.quit),
+		prompt.WithSearchKey(commitSearchKey),
+	)
+	if err := l.defineKeybindings(); err != nil {
+		return nil, err
+	}
+
+	return l.prompt, nil
+}
+
+// commitSearchKey matches the commits by their summaries and authors, as they
+// are displayed
+func commitSearchKey(item interface{}) string {
+	if c, ok := item.(*git.Commit); ok {
+		return c.Summary + " " + c.Author.Name
+	}
+	return fmt.Sprint(item)
+}
+
+// loadCommits returns a list that is filled with the commits of HEAD as they
+// are walked
+func loadCommits(r *git.Repository, size int) (*prompt.AsyncList, error) {
 	commits, err := r.CommitsChan(0)
 	if err != nil {
 		return nil, fmt.Errorf("could not load commits: %v", err)
 	}
-	r.Branches() // to find refs
-	r.Tags()
+	r.LoadRefs()
 	items := make(chan interface{})
 	go func() {
 		for c := range commits {
@@ -37,50 +73,33 @@ func LogPrompt(r *git.Repository, opts *prompt.Options) (*prompt.Prompt, error)
 		close(items)
 	}()
 
-	list, err := prompt.NewAsyncList(items, opts.LineSize)
+	list, err := prompt.NewAsyncList(items, size)
 	if err != nil {
 		return nil, fmt.Errorf("could not create list: %v", err)
 	}
+	return list, nil
+}
 
-	l := &log{repository: r}
-	l.prompt = prompt.Create("Commits", opts, list,
-		prompt.WithSelectionHandler(l.onSelect),
-		prompt.WithItemRenderer(renderItem),
-		prompt.WithInformation(l.logInfo),
-	)
-	if err := l.defineKeybindings(); err != nil {
-		return nil, err
+// reloadCommits lists the commits again from the top, e.g. to show the commit
+// created by a cherry-pick
+func (l *log) reloadCommits() error {
+	l.repository.LoadHead()
+	state := l.prompt.State()
+	list, err := loadCommits(l.repository, state.ListSize)
+	if err != nil {
+		return err
 	}
-
-	return l.prompt, nil
+	state.List = list
+	state.Cursor, state.Scroll, state.Selected = 0, 0, nil
+	l.prompt.SetState(state)
+	return nil
 }
 
 // return true to terminate
 func (l *log) onSelect(item interface{}) error {
 	switch item.(type) {
 	case *git.Commit: // nolint:typecheck
-		commit := item.(*git.Commit)
-		l.selected = commit
-		diff, err := commit.Diff()
-		if err != nil {
-			return nil
-		}
-		deltas := diff.Deltas()
-		if len(deltas) <= 0 {
-			return nil
-		}
-
-		l.oldState = l.prompt.State()
-		list, err := prompt.NewList(deltas, 5)
-		if err != nil {
-			return err
-		}
-		l.prompt.SetState(&prompt.State{
-			List:        list,
-			SearchMode:  false,
-			SearchStr:   "",
-			SearchLabel: "Files",
-		})
+		return l.commitDiff(item)
 	case *git.DiffDelta:
 		if l.selected == nil {
 			return nil
@@ -101,6 +120,36 @@ func (l *log) onSelect(item interface{}) error {
 	return nil
 }
 
+// showFiles replaces the list with the changed files of the commit
+func (l *log) showFiles(item interface{}) error {
+	commit, ok := item.(*git.Commit)
+	if !ok {
+		return nil
+	}
+	l.selected = commit
+	diff, err := commit.Diff()
+	if err != nil {
+		return nil
+	}
+	deltas := diff.Deltas()
+	if len(deltas) <= 0 {
+		return nil
+	}
+
+	l.oldState = l.prompt.State()
+	list, err := prompt.NewList(deltas, l.prompt.ListSize())
+	if err != nil {
+		return err
+	}
+	l.prompt.SetState(&prompt.State{
+		List:        list,
+		SearchMode:  false,
+		SearchStr:   "",
+		SearchLabel: "Files",
+	})
+	return nil
+}
+
 func (l *log) commitStat(item interface{}) error {
 	commit, ok := item.(*git.Commit)
 	if !ok {
@@ -120,12 +169,13 @@ func (l *log) commitDiff(item interface{}) error {
 }
 
 func (l *log) quit(item interface{}) error {
-	switch item.(type) {
-	case *git.Commit: // nolint: typecheck
-		l.prompt.Stop()
-	case *git.DiffDelta:
+	// the files of a commit are left even if none of them matches the search
+	if l.oldState != nil {
 		l.prompt.SetState(l.oldState)
+		l.oldState = nil
+		return nil
 	}
+	l.prompt.Stop()
 	return nil
 }
 
@@ -137,6 +187,9 @@ func (l *log) logInfo(item interface{}) [][]term.Cell {
 	switch item.(type) {
 	case *git.Commit: // nolint: typecheck
 		commit := item.(*git.Commit)
+		if l.rebasing {
+			grid = append(grid, term.Cprint(rebaseStopped, color.FgYellow))
+		}
 		cells := term.Cprint("Author ", color.Faint)
 		cells = append(cells, term.Cprint(commit.Author.Name+" <"+commit.Author.Email+">", color.FgWhite)...)
 		grid = append(grid, cells)
@@ -144,7 +197,19 @@ func (l *log) logInfo(item interface{}) [][]term.Cell {
 		cells = append(cells, term.Cprint("   "+timeago.FromTime(commit.Author.When), color.FgWhite)...)
 		grid = append(grid, cells)
 		grid = append(grid, commitRefs(l.repository, commit))
-		return grid
+		if body := commitBody(commit); len(body) > 0 {
+			grid = append(grid, nil)
+			for _, line := range strings.Split(body, "\n") {
+				grid = append(grid, term.Cprint(line, color.FgWhite))
+			}
+		}
+		if stats := l.commitStats(commit); len(stats) > 0 {
+			grid = append(grid, nil)
+			for _, line := range stats {
+				grid = append(grid, term.Cprint(line, color.Faint))
+			}
+		}
+		return append(grid, l.dry.info()...)
 	case *git.DiffDelta:
 		dd := item.(*git.DiffDelta)
 		var adds, dels int
@@ -178,8 +243,162 @@ func (l *log) logInfo(item interface{}) [][]term.Cell {
 	return grid
 }
 
+// commitStats returns the diff stats of the commit, they are cached since the
+// information is rendered on each key press
+func (l *log) commitStats(commit *git.Commit) []string {
+	if stats, ok := l.stats[commit.Hash]; ok {
+		return stats
+	}
+	var stats []string
+	if diff, err := commit.Diff(); err == nil {
+		for _, line := range diff.Stats() {
+			if line = strings.TrimRight(line, " "); len(line) > 0 {
+				stats = append(stats, line)
+			}
+		}
+	}
+	l.stats[commit.Hash] = stats
+	return stats
+}
+
+// commitBody returns the commit message without the summary line
+func commitBody(commit *git.Commit) string {
+	parts := strings.SplitN(commit.Message, "\n", 2)
+	if len(parts) < 2 {
+		return ""
+	}
+	return strings.TrimSpace(parts[1])
+}
+
+// cherryPick applies the changes of the commit onto HEAD, a conflict is left
+// for the user to resolve
+func (l *log) cherryPick(item interface{}) error {
+	commit, ok := item.(*git.Commit)
+	if !ok {
+		return nil
+	}
+	if ok, err := l.prompt.Confirm("Cherry-pick " + commit.Hash[:7] + " onto HEAD?"); !ok {
+		return err
+	}
+	return l.applyCommit([]string{"cherry-pick", commit.Hash}, "CHERRY_PICK_HEAD", "Cherry-picked "+commit.Hash[:7], false)
+}
+
+// revert creates a commit that undoes the changes of the commit, its message is
+// edited with the editor
+func (l *log) revert(item interface{}) error {
+	return l.bareRevert(item, "--edit")
+}
+
+// revertNoEdit reverts the commit with the default message
+func (l *log) revertNoEdit(item interface{}) error {
+	return l.bareRevert(item, "--no-edit")
+}
+
+func (l *log) bareRevert(item interface{}, mode string) error {
+	commit, ok := item.(*git.Commit)
+	if !ok {
+		return nil
+	}
+	if ok, err := l.prompt.Confirm("Revert " + commit.Hash[:7] + "?"); !ok {
+		return err
+	}
+	return l.applyCommit([]string{"revert", mode, commit.Hash}, "REVERT_HEAD", "Reverted "+commit.Hash[:7], mode == "--edit")
+}
+
+const rebaseStopped = "Rebase in progress, press C to continue or A to abort"
+
+// rebase edits the commits after the commit with the todo list of git in the
+// editor
+func (l *log) rebase(item interface{}) error {
+	commit, ok := item.(*git.Commit)
+	if !ok {
+		return nil
+	}
+	if l.rebasing {
+		showMessage(l.prompt, rebaseStopped)
+		return nil
+	}
+	return l.runRebase([]string{"rebase", "--interactive", commit.Hash})
+}
+
+func (l *log) continueRebase(item interface{}) error {
+	if !l.rebasing {
+		showMessage(l.prompt, "No rebase in progress")
+		return nil
+	}
+	return l.runRebase([]string{"rebase", "--continue"})
+}
+
+func (l *log) abortRebase(item interface{}) error {
+	if !l.rebasing {
+		showMessage(l.prompt, "No rebase in progress")
+		return nil
+	}
+	if ok, err := l.prompt.Confirm("Abort the rebase?"); !ok {
+		return err
+	}
+	return l.runRebase([]string{"rebase", "--abort"})
+}
+
+// runRebase runs the rebase command attached to the terminal since it opens the
+// editor, and lists the commits again. The rebase may stop for a conflict or to
+// edit a commit, which is told to the user until a key is pressed.
+func (l *log) runRebase(args []string) error {
+	if l.dry.skip(l.prompt, args) {
+		return nil
+	}
+	err := popGitCommand(l.repository, args)
+	l.rebasing = rebaseInProgress(l.repository)
+	switch {
+	case l.rebasing:
+		l.prompt.SetMessage(term.Cprint(rebaseStopped, color.FgYellow), 0)
+	case err != nil:
+		showError(l.prompt, err)
+	default:
+		showMessage(l.prompt, "Rebased successfully")
+	}
+	return l.reloadCommits()
+}
+
+// applyCommit runs the git command that creates a commit from another one, like
+// a cherry-pick, and lists the commits again to show it. If it stops because of
+// a conflict, head is the ref it leaves behind and the user is told to resolve
+// it. The command is attached to the terminal if it opens the editor.
+func (l *log) applyCommit(args []string, head, msg string, editor bool) error {
+	if l.dry.skip(l.prompt, args) {
+		return nil
+	}
+	run := runGitCommand
+	if editor {
+		run = popGitCommand
+	}
+	if err := run(l.repository, args); err != nil {
+		if inProgress(l.repository, head) {
+			showConflict(l.prompt, args[0])
+		} else {
+			showError(l.prompt, err)
+		}
+		return nil
+	}
+	showMessage(l.prompt, msg)
+	return l.reloadCommits()
+}
+
+// reverse toggles the oldest commits first
+func (l *log) reverse(item interface{}) error {
+	l.prompt.Reverse()
+	return nil
+}
+
 func (l *log) defineKeybindings() error {
 	keybindings := []*prompt.KeyBinding{
+		&prompt.KeyBinding{
+			Key:     'f',
+			Display: "f",
+			Desc:    "show files",
+			Primary: true,
+			Handler: l.showFiles,
+		},
 		&prompt.KeyBinding{
 			Key:     's',
 			Display: "s",
@@ -190,14 +409,52 @@ func (l *log) defineKeybindings() error {
 			Key:     'd',
 			Display: "d",
 			Desc:    "show diff",
+			Primary: true,
 			Handler: l.commitDiff,
 		},
 		&prompt.KeyBinding{
-			Key:     'q',
-			Display: "q",
-			Desc:    "quit",
-			Handler: l.quit,
+			Key:     'r',
+			Display: "r",
+			Desc:    "reverse order",
+			Handler: l.reverse,
+		},
+		&prompt.KeyBinding{
+			Key:     'c',
+			Display: "c",
+			Desc:    "cherry-pick commit",
+			Handler: l.cherryPick,
+		},
+		&prompt.KeyBinding{
+			Key:     'v',
+			Display: "v",
+			Desc:    "revert commit",
+			Handler: l.revert,
+		},
+		&prompt.KeyBinding{
+			Key:     'V',
+			Display: "V",
+			Desc:    "revert commit without editing the message",
+			Handler: l.revertNoEdit,
+		},
+		&prompt.KeyBinding{
+			Key:     'i',
+			Display: "i",
+			Desc:    "rebase interactively onto commit",
+			Handler: l.rebase,
+		},
+		&prompt.KeyBinding{
+			Key:     'C',
+			Display: "C",
+			Desc:    "continue rebase",
+			Handler: l.continueRebase,
+		},
+		&prompt.KeyBinding{
+			Key:     'A',
+			Display: "A",
+			Desc:    "abort rebase",
+			Handler: l.abortRebase,
 		},
+		copyBinding(l.prompt),
 	}
 	for _, kb := range keybindings {
 		if err := l.prompt.AddKeyBinding(kb); err != nil {
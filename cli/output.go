@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/isacikgoz/gitin/git"
+	"github.com/isacikgoz/gitin/prompt"
+	"github.com/isacikgoz/gitin/term"
+)
+
+const (
+	// outputSize is the number of the displayed output lines of a command,
+	// including the command itself
+	outputSize = 6
+	// outputHistory is the number of the output lines kept to scroll back
+	outputHistory = 1000
+)
+
+// commandOutput runs the long running git commands in the background, like a
+// push. Their output is kept to be displayed in the information of the prompt
+// while they run, so the user sees the progress instead of a frozen prompt.
+type commandOutput struct {
+	prompt  *prompt.Prompt
+	command string
+	lines   []string
+	scroll  int // number of the lines scrolled back from the end
+	running bool
+}
+
+// run starts the command unless another one is running, done is called by the
+// main loop of the prompt with the exit status when the command finishes
+func (o *commandOutput) run(r *git.Repository, args []string, done func(err error)) {
+	if o.running {
+		return
+	}
+	o.running = true
+	o.show("git " + strings.Join(args, " "))
+	go func() {
+		err := streamGitCommand(r, args, func(line string, replace bool) {
+			o.prompt.Post(func() {
+				if n := len(o.lines); replace && n > 0 {
+					o.lines = o.lines[:n-1]
+				}
+				o.lines = append(o.lines, line)
+				if n := len(o.lines); n > outputHistory {
+					o.lines = o.lines[n-outputHistory:]
+				}
+				o.prompt.InvalidateInformation()
+			})
+		})
+		o.prompt.Post(func() {
+			o.running = false
+			if err != nil {
+				o.lines = append(o.lines, "error: "+err.Error())
+			}
+			o.prompt.InvalidateInformation()
+			if done != nil {
+				done(err)
+			}
+		})
+	}()
+}
+
+// show replaces the output with the line, e.g. to tell why a command is not
+// run
+func (o *commandOutput) show(line string) error {
+	o.command, o.lines, o.scroll = line, nil, 0
+	o.prompt.InvalidateInformation()
+	return nil
+}
+
+// scrollBy moves the displayed lines back, or forward if n is negative
+func (o *commandOutput) scrollBy(n int) {
+	o.scroll += n
+	if max := len(o.lines) - (outputSize - 1); o.scroll > max {
+		o.scroll = max
+	}
+	if o.scroll < 0 {
+		o.scroll = 0
+	}
+	o.prompt.InvalidateInformation()
+}
+
+// cells returns the command and the displayed lines of its output
+func (o *commandOutput) cells() [][]term.Cell {
+	if len(o.command) == 0 {
+		return nil
+	}
+	grid := [][]term.Cell{term.Cprint(o.command, color.Faint)}
+	end := len(o.lines) - o.scroll
+	start := end - (outputSize - 1)
+	if start < 0 {
+		start = 0
+	}
+	for _, line := range o.lines[start:end] {
+		attr := color.Faint
+		if strings.HasPrefix(line, "error: ") {
+			attr = color.FgRed
+		}
+		grid = append(grid, term.Cprint(line, attr))
+	}
+	return grid
+}
@@ -0,0 +1,190 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/isacikgoz/gitin/git"
+	"github.com/isacikgoz/gitin/prompt"
+	"github.com/isacikgoz/gitin/term"
+	"github.com/justincampbell/timeago"
+)
+
+// reflog holds the repository struct and the prompt pointer.
+type reflog struct {
+	repository *git.Repository
+	prompt     *prompt.Prompt
+	ref        string
+}
+
+// reflogEntry is a change of a ref, Old is empty for the first entry
+type reflogEntry struct {
+	Selector string
+	Old      string
+	New      string
+	Action   string
+	Message  string
+	When     time.Time
+}
+
+func (e *reflogEntry) String() string {
+	return e.Action + ": " + e.Message
+}
+
+// Identity matches the entry after a reset, which shifts the selectors by one
+func (e *reflogEntry) Identity() string {
+	return e.New + " " + strconv.FormatInt(e.When.UnixNano(), 10)
+}
+
+// ReflogPrompt configures a prompt to serve as a reflog prompt of the ref, HEAD
+// is used if ref is empty
+func ReflogPrompt(r *git.Repository, opts *Options, ref string) (*prompt.Prompt, error) {
+	if len(ref) == 0 {
+		ref = "HEAD"
+	}
+	entries, err := loadReflog(r, ref)
+	if err != nil {
+		return nil, fmt.Errorf("could not load reflog: %v", err)
+	}
+	if len(entries) == 0 {
+		return nil, &EmptyError{Message: noReflog(ref)}
+	}
+	list, err := prompt.NewList(entries, lineSize(opts))
+	if err != nil {
+		return nil, fmt.Errorf("could not create list: %v", err)
+	}
+
+	rl := &reflog{repository: r, ref: ref}
+	rl.prompt = prompt.Create("Reflog of "+ref, &opts.Options, list,
+		prompt.WithSelectionHandler(rl.onSelect),
+		prompt.WithItemRenderer(itemRenderer(opts)),
+		prompt.WithEmptyMessage(noMatches("entries")),
+		prompt.WithInformation(rl.info),
+	)
+	if err := rl.defineKeybindings(); err != nil {
+		return nil, err
+	}
+
+	return rl.prompt, nil
+}
+
+// loadReflog reads the reflog of the ref with git since libgit2 bindings do not
+// expose it, the latest entry comes first
+func loadReflog(r *git.Repository, ref string) ([]*reflogEntry, error) {
+	// the selectors are dated like HEAD@{1700000000} to tell when the ref has
+	// changed, the commit time is of the commit that may be much older
+	cmd := gitCommand(r, "reflog", "show", "--date=unix", "--format=%gd%x1f%H%x1f%gs", ref, "--")
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	return parseReflog(string(out)), nil
+}
+
+// parseReflog reads the entries of the reflog output, the dated selectors are
+// replaced by the numbered ones like HEAD@{2}
+func parseReflog(out string) []*reflogEntry {
+	entries := make([]*reflogEntry, 0)
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		fields := strings.Split(line, "\x1f")
+		if len(fields) != 3 {
+			continue
+		}
+		e := &reflogEntry{
+			Selector: fields[0],
+			New:      fields[1],
+			Action:   fields[2],
+		}
+		if i := strings.LastIndex(fields[0], "@{"); i > 0 && strings.HasSuffix(fields[0], "}") {
+			if sec, err := strconv.ParseInt(fields[0][i+2:len(fields[0])-1], 10, 64); err == nil {
+				e.When = time.Unix(sec, 0)
+			}
+			e.Selector = fields[0][:i] + "@{" + strconv.Itoa(len(entries)) + "}"
+		}
+		// subjects are in "<action>: <message>" form, e.g. "checkout: moving from a to b"
+		if i := strings.Index(fields[2], ": "); i > 0 {
+			e.Action = fields[2][:i]
+			e.Message = fields[2][i+2:]
+		}
+		if n := len(entries); n > 0 {
+			entries[n-1].Old = e.New
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func (rl *reflog) onSelect(item interface{}) error {
+	entry := item.(*reflogEntry)
+	return popGitCommand(rl.repository, []string{"show", entry.New})
+}
+
+func (rl *reflog) info(item interface{}) [][]term.Cell {
+	entry := item.(*reflogEntry)
+	grid := make([][]term.Cell, 0)
+	cells := term.Cprint(entry.Selector+" ", color.FgYellow)
+	if len(entry.Old) > 0 {
+		cells = append(cells, term.Cprint(entry.Old[:7]+" → ", color.Faint)...)
+	}
+	cells = append(cells, term.Cprint(entry.New[:7], color.FgCyan)...)
+	grid = append(grid, cells)
+	if !entry.When.IsZero() {
+		cells = term.Cprint("Changed ", color.Faint)
+		cells = append(cells, term.Cprint(timeago.FromTime(entry.When), color.FgBlue)...)
+		grid = append(grid, cells)
+	}
+	return grid
+}
+
+func (rl *reflog) defineKeybindings() error {
+	keybindings := []*prompt.KeyBinding{
+		&prompt.KeyBinding{
+			Key:     'R',
+			Display: "R",
+			Desc:    "reset to entry",
+			Primary: true,
+			Handler: rl.resetEntry,
+		},
+		copyBinding(rl.prompt),
+	}
+	for _, kb := range keybindings {
+		if err := rl.prompt.AddKeyBinding(kb); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resetEntry moves HEAD to the entry, the local changes are kept and the reset
+// is refused by git if they would be overwritten
+func (rl *reflog) resetEntry(item interface{}) error {
+	entry := item.(*reflogEntry)
+	if ok, err := rl.prompt.Confirm("Reset HEAD to " + entry.Selector + "?"); !ok {
+		return err
+	}
+	// git refuses the reset if the local changes would be overwritten
+	if err := runGitCommand(rl.repository, []string{"reset", "--keep", entry.New}); err != nil {
+		showError(rl.prompt, err)
+		return nil
+	}
+	return rl.reloadReflog()
+}
+
+// reloads the list
+func (rl *reflog) reloadReflog() error {
+	entries, err := loadReflog(rl.repository, rl.ref)
+	if err != nil {
+		return err
+	}
+	state := rl.prompt.State()
+	list, err := prompt.NewList(entries, state.ListSize)
+	if err != nil {
+		return err
+	}
+	state.List = list
+	rl.prompt.SetState(state)
+	return nil
+}
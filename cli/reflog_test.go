@@ -0,0 +1,32 @@
+package cli
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseReflog(t *testing.T) {
+	out := "HEAD@{1700000200}\x1fbbb\x1fcheckout: moving from main to topic\n" +
+		"HEAD@{1700000100}\x1faaa\x1fcommit (initial): first\n"
+	var tests = []struct {
+		selector, old, new, action string
+		when                       int64
+	}{
+		{"HEAD@{0}", "aaa", "bbb", "checkout", 1700000200},
+		{"HEAD@{1}", "", "aaa", "commit (initial)", 1700000100},
+	}
+	entries := parseReflog(out)
+	if len(entries) != len(tests) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(tests))
+	}
+	for i, test := range tests {
+		e := entries[i]
+		if e.Selector != test.selector || e.Old != test.old || e.New != test.new || e.Action != test.action {
+			t.Errorf("entry %d: got %s %s %s %q, want %s %s %s %q", i, e.Selector, e.Old, e.New, e.Action,
+				test.selector, test.old, test.new, test.action)
+		}
+		if !e.When.Equal(time.Unix(test.when, 0)) {
+			t.Errorf("entry %d: changed at %v, want the time of the entry %v", i, e.When, time.Unix(test.when, 0))
+		}
+	}
+}
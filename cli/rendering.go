@@ -7,9 +7,14 @@ import (
 	"github.com/fatih/color"
 	"github.com/isacikgoz/gitin/git"
 	"github.com/isacikgoz/gitin/term"
+	"github.com/justincampbell/timeago"
+	"github.com/waigani/diffparser"
 )
 
 func renderItem(item interface{}, matches []int, selected bool) [][]term.Cell {
+	if h, ok := item.(*statusHeader); ok {
+		return [][]term.Cell{term.Cprint(h.String(), color.Bold, color.Faint)}
+	}
 	var line []term.Cell
 	if selected {
 		line = append(line, term.Cprint("> ", color.FgCyan)...)
@@ -18,18 +23,74 @@ func renderItem(item interface{}, matches []int, selected bool) [][]term.Cell {
 	}
 	switch i := item.(type) {
 	case *git.StatusEntry: // nolint: typecheck
+		if i.Conflicted() {
+			line = append(line, conflictText(matches, i.String())...)
+			break
+		}
+		// colored like git status does, the files out of the index are gray
 		attr := color.FgRed
 		if i.Indexed() {
 			attr = color.FgGreen
+		} else if i.Untracked() {
+			attr = color.Faint
 		}
 		line = append(line, stautsText(i.StatusEntryString()[:1])...)
 		line = append(line, highLightedText(matches, attr, i.String())...)
 	case *git.Commit:
 		line = append(line, stautsText(i.Hash[:7])...)
 		line = append(line, highLightedText(matches, color.FgWhite, i.String())...)
+		line = append(line, term.Cprint(" ", color.Faint)...)
+		line = append(line, highLightedText(shiftMatches(matches, len(i.String())+1), color.Faint, i.Author.Name)...)
+		line = append(line, term.Cprint(", "+timeago.FromTime(i.Author.When), color.Faint)...)
+	case *git.Stash:
+		line = append(line, stautsText(i.Ref())...)
+		line = append(line, highLightedText(matches, color.FgWhite, i.String())...)
+	case *git.Tag:
+		attr, kind := color.FgWhite, "l"
+		if i.Annotated {
+			attr, kind = color.FgYellow, "a"
+		}
+		line = append(line, stautsText(kind)...)
+		line = append(line, highLightedText(matches, attr, i.String())...)
+		if commit := i.Target(); commit != nil {
+			line = append(line, term.Cprint(" "+commit.Summary, color.Faint)...)
+		}
+	case *blameLine:
+		line = append(line, blameGutter(i)...)
+		line = append(line, highLightedText(matches, color.FgWhite, i.Content)...)
+	case *reflogEntry:
+		line = append(line, stautsText(i.New[:7])...)
+		line = append(line, term.Cprint(i.Action+": ", color.FgYellow)...)
+		line = append(line, highLightedText(shiftMatches(matches, len(i.Action)+2), color.FgWhite, i.Message)...)
+		line = append(line, term.Cprint(" "+timeago.FromTime(i.When), color.Faint)...)
 	case *git.DiffDelta:
 		line = append(line, stautsText(i.DeltaStatusString()[:1])...)
 		line = append(line, highLightedText(matches, color.FgWhite, i.String())...)
+	case *hunk:
+		mark := " "
+		if i.selected {
+			mark = "x"
+		}
+		line = append(line, stautsText(mark)...)
+		line = append(line, highLightedText(matches, color.FgCyan, i.String())...)
+	case *hunkLine:
+		mark := " "
+		if i.selected {
+			mark = "x"
+		}
+		attr := color.Faint
+		switch {
+		case !i.changed():
+			mark = "-"
+		case i.essence.Mode == diffparser.ADDED:
+			attr = color.FgGreen
+		default:
+			attr = color.FgRed
+		}
+		line = append(line, stautsText(mark)...)
+		line = append(line, highLightedText(matches, attr, i.String())...)
+	case *splitRow:
+		line = append(line, i.cells()...)
 	case *git.Branch:
 		attr := color.FgWhite
 		headIndicator := ""
@@ -58,27 +119,53 @@ func stautsText(text string) []term.Cell {
 	return cells
 }
 
-func highLightedText(matches []int, c color.Attribute, str string) []term.Cell {
-	if len(matches) == 0 {
-		return term.Cprint(str, c)
-	}
-	highligted := make([]term.Cell, 0)
-	for _, r := range str {
-		highligted = append(highligted, term.Cell{
-			Ch:   r,
-			Attr: []color.Attribute{c},
-		})
+// conflictText marks the path of a conflicted entry so that it stands out of
+// the other unstaged ones
+func conflictText(matches []int, path string) []term.Cell {
+	cells := []term.Cell{{Ch: '['}}
+	cells = append(cells, term.Cprint("!", color.FgRed, color.Bold)...)
+	cells = append(cells, term.Cprint("] ", color.FgWhite)...)
+	cells = append(cells, term.Highlight(path, matches, color.FgRed, color.Bold)...)
+	return append(cells, term.Cprint(" (conflict)", color.Faint)...)
+}
+
+// stateInfo returns the operation that the repository is in the middle of,
+// e.g. a merge that has stopped with conflicts
+func stateInfo(state git.State) [][]term.Cell {
+	var name string
+	switch state {
+	case git.StateMerge:
+		name = "MERGING"
+	case git.StateRebase, git.StateRebaseInteractive, git.StateRebaseMerge, git.StateApplyMailboxOrRebase:
+		name = "REBASING"
+	case git.StateCherrypick:
+		name = "CHERRY-PICKING"
+	case git.StateRevert:
+		name = "REVERTING"
+	case git.StateBisect:
+		name = "BISECTING"
+	case git.StateApplyMailbox:
+		name = "AM"
+	default:
+		return nil
 	}
-	for _, m := range matches {
-		if m > len(highligted)-1 {
-			continue
-		}
-		highligted[m] = term.Cell{
-			Ch:   highligted[m].Ch,
-			Attr: append(highligted[m].Attr, color.Underline),
-		}
+	return [][]term.Cell{term.Cprint(name, color.FgRed, color.Bold)}
+}
+
+func highLightedText(matches []int, c color.Attribute, str string) []term.Cell {
+	return term.Highlight(str, matches, c)
+}
+
+// statLine renders the numbers of the added and the deleted lines of a change,
+// git counts the lines of a binary file as -
+func statLine(label, added, deleted string) []term.Cell {
+	line := term.Cprint(label+": ", color.Faint)
+	if added == "-" {
+		return append(line, term.Cprint("binary", color.Faint)...)
 	}
-	return highligted
+	line = append(line, term.Cprint("+"+added, color.FgGreen)...)
+	line = append(line, term.Cell{Ch: ' '})
+	return append(line, term.Cprint("-"+deleted, color.FgRed)...)
 }
 
 func branchInfo(b *git.Branch, yours bool) [][]term.Cell {
@@ -146,3 +233,33 @@ func workingTreeClean(b *git.Branch) [][]term.Cell {
 	grid = append(grid, term.Cprint("Nothing to commit, working tree clean", color.Faint))
 	return grid
 }
+
+// noMatches is displayed if none of the things listed by a prompt match the
+// search
+func noMatches(things string) []term.Cell {
+	return term.Cprint("No "+things+" match the search", color.FgRed)
+}
+
+func noStashes() [][]term.Cell {
+	return [][]term.Cell{term.Cprint("No stash entries found", color.Faint)}
+}
+
+func noTags() [][]term.Cell {
+	return [][]term.Cell{term.Cprint("No tags found", color.Faint)}
+}
+
+func noReflog(ref string) [][]term.Cell {
+	return [][]term.Cell{term.Cprint("No reflog entries found for "+ref, color.Faint)}
+}
+
+// shiftMatches moves the matched byte offsets back by n, the ones before it
+// are dropped. It is used if only a suffix of the searched string is rendered.
+func shiftMatches(matches []int, n int) []int {
+	var shifted []int
+	for _, m := range matches {
+		if m >= n {
+			shifted = append(shifted, m-n)
+		}
+	}
+	return shifted
+}
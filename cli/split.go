@@ -0,0 +1,174 @@
+package cli
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/isacikgoz/gitin/term"
+	"github.com/waigani/diffparser"
+)
+
+const (
+	// cursor indicator of the item and the scrollbar of the prompt
+	splitMargin = 4
+	splitNumber = 5
+	splitSep    = " │ "
+)
+
+// splitRow is a line of the side-by-side diff, a long line is wrapped into
+// rows that continue without the line number
+type splitRow struct {
+	header      string // set if the row separates the hunks
+	left, right []term.Cell
+}
+
+func (r *splitRow) String() string {
+	if len(r.header) > 0 {
+		return r.header
+	}
+	return cellsString(r.left) + " " + cellsString(r.right)
+}
+
+func (r *splitRow) cells() []term.Cell {
+	if len(r.header) > 0 {
+		return term.Cprint(r.header, color.FgCyan, color.Faint)
+	}
+	line := append([]term.Cell{}, r.left...)
+	line = append(line, term.Cprint(splitSep, color.Faint)...)
+	return append(line, r.right...)
+}
+
+// splitDiff renders the hunks of the file into two columns fitting the width,
+// the removed lines are on the left and the added ones are on the right. If
+// words is set, only the changed words of the paired lines are highlighted.
+func splitDiff(file *diffparser.DiffFile, width int, words bool) []*splitRow {
+	column := (width - splitMargin - len(splitSep)) / 2
+	if column < splitNumber+1 {
+		column = splitNumber + 1
+	}
+	var rows []*splitRow
+	for _, h := range file.Hunks {
+		rows = append(rows, &splitRow{header: (&hunk{essence: h}).String()})
+		var removed, added []*diffparser.DiffLine
+		orig := h.OrigRange.Start // the context lines are numbered by the new file
+		flush := func() {
+			for i := 0; i < len(removed) || i < len(added); i++ {
+				var l, r *diffparser.DiffLine
+				if i < len(removed) {
+					l = removed[i]
+				}
+				if i < len(added) {
+					r = added[i]
+				}
+				rows = append(rows, splitLines(l, r, column, words)...)
+			}
+			removed, added = nil, nil
+		}
+		for _, line := range h.WholeRange.Lines {
+			switch line.Mode {
+			case diffparser.REMOVED:
+				if len(added) > 0 {
+					flush()
+				}
+				removed = append(removed, line)
+				orig++
+			case diffparser.ADDED:
+				added = append(added, line)
+			default:
+				flush()
+				left := *line
+				left.Number = orig
+				rows = append(rows, splitLines(&left, line, column, false)...)
+				orig++
+			}
+		}
+		flush()
+	}
+	return rows
+}
+
+// splitLines pairs the lines into rows, nil leaves its side empty
+func splitLines(left, right *diffparser.DiffLine, column int, words bool) []*splitRow {
+	var lchanged, rchanged []bool
+	if words && left != nil && right != nil {
+		lchanged, rchanged = wordDiff(expandTabs(left.Content), expandTabs(right.Content))
+	}
+	l := splitColumn(left, lchanged, column)
+	r := splitColumn(right, rchanged, column)
+	rows := make([]*splitRow, 0, len(l))
+	for i := 0; i < len(l) || i < len(r); i++ {
+		row := &splitRow{left: blankCells(column), right: blankCells(column)}
+		if i < len(l) {
+			row.left = l[i]
+		}
+		if i < len(r) {
+			row.right = r[i]
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// splitColumn wraps the line into the column width, each wrapped part is
+// padded so that the right column is aligned. If changed is set, the unchanged
+// runes are not colored.
+func splitColumn(line *diffparser.DiffLine, changed []bool, column int) [][]term.Cell {
+	if line == nil {
+		return nil
+	}
+	attr := color.FgWhite
+	switch line.Mode {
+	case diffparser.ADDED:
+		attr = color.FgGreen
+	case diffparser.REMOVED:
+		attr = color.FgRed
+	}
+	number := strconv.Itoa(line.Number)
+	prefix := term.Cprint(strings.Repeat(" ", splitNumber-1-len(number))+number+" ", color.Faint)
+	content := term.Cprint(expandTabs(line.Content), attr)
+	for i := range changed {
+		if !changed[i] {
+			content[i].Attr = []color.Attribute{color.FgWhite}
+		}
+	}
+
+	var parts [][]term.Cell
+	for {
+		part := append([]term.Cell{}, prefix...)
+		w := term.Width(part)
+		n := 0
+		for n < len(content) && w+content[n].Width() <= column {
+			w += content[n].Width()
+			n++
+		}
+		part = append(part, content[:n]...)
+		content = content[n:]
+		parts = append(parts, append(part, blankCells(column-w)...))
+		if len(content) == 0 || n == 0 {
+			break
+		}
+		prefix = blankCells(splitNumber)
+	}
+	return parts
+}
+
+func expandTabs(s string) string {
+	return strings.ReplaceAll(s, "\t", "    ")
+}
+
+func blankCells(n int) []term.Cell {
+	cells := make([]term.Cell, 0, n)
+	for i := 0; i < n; i++ {
+		cells = append(cells, term.Cell{Ch: ' '})
+	}
+	return cells
+}
+
+func cellsString(cells []term.Cell) string {
+	var b strings.Builder
+	for _, c := range cells {
+		b.WriteRune(c.Ch)
+	}
+	return strings.TrimSpace(b.String())
+}
@@ -0,0 +1,165 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/isacikgoz/gitin/git"
+	"github.com/isacikgoz/gitin/prompt"
+	"github.com/isacikgoz/gitin/term"
+	"github.com/justincampbell/timeago"
+)
+
+// stash holds the repository struct and the prompt pointer.
+type stash struct {
+	repository *git.Repository
+	prompt     *prompt.Prompt
+	stats      map[string][]string // diff stats of the stashes by hash
+}
+
+// StashPrompt configures a prompt to serve as a stash prompt
+func StashPrompt(r *git.Repository, opts *Options) (*prompt.Prompt, error) {
+	stashes, err := r.Stashes()
+	if err != nil {
+		return nil, fmt.Errorf("could not load stashes: %v", err)
+	}
+	if len(stashes) == 0 {
+		return nil, &EmptyError{Message: noStashes()}
+	}
+	list, err := prompt.NewList(stashes, lineSize(opts))
+	if err != nil {
+		return nil, fmt.Errorf("could not create list: %v", err)
+	}
+
+	s := &stash{repository: r, stats: make(map[string][]string)}
+	s.prompt = prompt.Create("Stashes", &opts.Options, list,
+		prompt.WithSelectionHandler(s.onSelect),
+		prompt.WithItemRenderer(itemRenderer(opts)),
+		prompt.WithEmptyMessage(noMatches("stashes")),
+		prompt.WithInformation(s.info),
+	)
+	if err := s.defineKeybindings(opts); err != nil {
+		return nil, err
+	}
+
+	return s.prompt, nil
+}
+
+func (s *stash) onSelect(item interface{}) error {
+	entry := item.(*git.Stash)
+	return s.runCommandWithArgs([]string{"stash", "apply", "--quiet", entry.Ref()})
+}
+
+func (s *stash) info(item interface{}) [][]term.Cell {
+	entry := item.(*git.Stash)
+	grid := make([][]term.Cell, 0)
+	if len(entry.Branch) > 0 {
+		cells := term.Cprint("On branch ", color.Faint)
+		cells = append(cells, term.Cprint(entry.Branch, color.FgYellow)...)
+		grid = append(grid, cells)
+	}
+	commit := entry.Target()
+	if commit == nil {
+		return grid
+	}
+	cells := term.Cprint("Stashed ", color.Faint)
+	cells = append(cells, term.Cprint(timeago.FromTime(commit.Author.When), color.FgBlue)...)
+	grid = append(grid, cells)
+	for _, line := range s.diffStats(commit) {
+		grid = append(grid, term.Cprint(line, color.Faint))
+	}
+	return grid
+}
+
+// diffStats returns the changes of the stash compared to the commit it is
+// created on, they are cached since the information is rendered on each key press
+func (s *stash) diffStats(commit *git.Commit) []string {
+	if stats, ok := s.stats[commit.Hash]; ok {
+		return stats
+	}
+	var stats []string
+	if diff, err := commit.Diff(); err == nil {
+		for _, line := range diff.Stats() {
+			if line = strings.TrimRight(line, " "); len(line) > 0 {
+				stats = append(stats, line)
+			}
+		}
+	}
+	s.stats[commit.Hash] = stats
+	return stats
+}
+
+func (s *stash) defineKeybindings(opts *Options) error {
+	keybindings := []*prompt.KeyBinding{
+		&prompt.KeyBinding{
+			Key:     'p',
+			Display: "p",
+			Desc:    "pop stash",
+			Primary: true,
+			Handler: s.popStash,
+		},
+		deleteBinding(opts, "drop stash", s.dropStash),
+		&prompt.KeyBinding{
+			Key:     's',
+			Display: "s",
+			Desc:    "stash changes",
+			Primary: true,
+			Handler: s.saveStash,
+		},
+		copyBinding(s.prompt),
+	}
+	for _, kb := range keybindings {
+		if err := s.prompt.AddKeyBinding(kb); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *stash) popStash(item interface{}) error {
+	entry := item.(*git.Stash)
+	return s.runCommandWithArgs([]string{"stash", "pop", "--quiet", entry.Ref()})
+}
+
+func (s *stash) dropStash(item interface{}) error {
+	entry := item.(*git.Stash)
+	if ok, err := s.prompt.Confirm("Drop " + entry.Ref() + "?"); !ok {
+		return err
+	}
+	return s.runCommandWithArgs([]string{"stash", "drop", "--quiet", entry.Ref()})
+}
+
+func (s *stash) saveStash(item interface{}) error {
+	return s.runCommandWithArgs([]string{"stash", "push", "--quiet"})
+}
+
+func (s *stash) runCommandWithArgs(args []string) error {
+	if err := runGitCommand(s.repository, args); err != nil {
+		showError(s.prompt, err) // possibly conflicts or nothing to stash
+		return nil
+	}
+	return s.reloadStashes()
+}
+
+// reloads the list
+func (s *stash) reloadStashes() error {
+	stashes, err := s.repository.Stashes()
+	if err != nil {
+		return err
+	}
+	if len(stashes) == 0 {
+		// this is the case when the last stash is dropped at runtime
+		s.prompt.Stop()
+		s.prompt.SetExitMsg(noStashes())
+		return nil
+	}
+	state := s.prompt.State()
+	list, err := prompt.NewList(stashes, state.ListSize)
+	if err != nil {
+		return err
+	}
+	state.List = list
+	s.prompt.SetState(state)
+	return nil
+}
@@ -1,11 +1,16 @@
 package cli
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"os"
-	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 
+	"github.com/fatih/color"
 	"github.com/isacikgoz/gia/editor"
 	"github.com/isacikgoz/gitin/git"
 	"github.com/isacikgoz/gitin/prompt"
@@ -17,34 +22,61 @@ import (
 type status struct {
 	repository *git.Repository
 	prompt     *prompt.Prompt
+	dry        dryRun
+	state      git.State // e.g. a merge that has stopped with conflicts
+	headers    bool      // the files are listed under the headers of their sections
+	stale      bool      // the working tree has changed while a sub-list is displayed
+	filter     int       // index of the applied statusFilters
+	show       git.StatusOptions
+	entries    []*git.StatusEntry
+	stats      map[*git.StatusEntry]*lineStat // the diffstats of the entries
+
+	// set while the hunks of an entry are listed
+	entry    *git.StatusEntry
+	file     *diffparser.DiffFile
+	hunks    []*hunk
+	oldState *prompt.State
+
+	// set while the lines of a hunk are listed
+	hunk      *hunk
+	hunkState *prompt.State
+
+	// set while the side-by-side diff is displayed
+	split    *diffparser.DiffFile
+	wordDiff bool
 }
 
 // StatusPrompt configures a prompt to serve as work-dir explorer prompt
-func StatusPrompt(r *git.Repository, opts *prompt.Options) (*prompt.Prompt, error) {
-	st, err := r.LoadStatus()
+func StatusPrompt(r *git.Repository, opts *Options) (*prompt.Prompt, error) {
+	show := git.DefaultStatusOptions
+	st, err := r.LoadStatus(&show)
 	if err != nil {
 		return nil, fmt.Errorf("could not load status: %v", err)
 	}
 	if len(st.Entities) == 0 {
-		writer := term.NewBufferedWriter(os.Stdout)
-		for _, line := range workingTreeClean(r.Head) {
-			writer.WriteCells(line)
-		}
-		writer.Flush()
-		os.Exit(0)
+		return nil, &EmptyError{Message: workingTreeClean(r.Head)}
 	}
-	list, err := prompt.NewList(st.Entities, opts.LineSize)
+	// the headers would be listed after their sections in the reverse order
+	headers := !opts.Reverse
+	list, err := prompt.NewList(statusItems(st.Entities, headers), lineSize(opts))
 	if err != nil {
 		return nil, fmt.Errorf("could not create list: %v", err)
 	}
 
-	s := &status{repository: r}
+	s := &status{repository: r, dry: dryRun{enabled: opts.DryRun}, state: st.State, headers: headers, entries: st.Entities, show: show}
+	s.stats = make(map[*git.StatusEntry]*lineStat)
 
-	s.prompt = prompt.Create("Files", opts, list,
+	s.prompt = prompt.Create(statusLabel(r.Head), &opts.Options, list,
 		prompt.WithSelectionHandler(s.onSelect),
-		prompt.WithItemRenderer(renderItem),
+		prompt.WithItemRenderer(itemRenderer(opts)),
+		prompt.WithEmptyMessage(noMatches("files")),
 		prompt.WithInformation(s.info),
+		prompt.WithQuitHandler(s.quit),
+		prompt.WithSortOrders(statusSortOrders...),
 	)
+	if opts.Watch > 0 {
+		s.prompt.AddJob(s.watch(opts.Watch))
+	}
 	if err := s.defineKeybindings(); err != nil {
 		return nil, err
 	}
@@ -52,70 +84,343 @@ func StatusPrompt(r *git.Repository, opts *prompt.Options) (*prompt.Prompt, erro
 	return s.prompt, nil
 }
 
+// statusLabel names the files after the branch and how far it is from its
+// upstream, like "Files on main ↑2 ↓1 origin/main"
+func statusLabel(b *git.Branch) string {
+	if b == nil || len(b.Name) == 0 {
+		return "Files"
+	}
+	label := "Files on " + b.Name
+	if b.Upstream == nil {
+		return label + ", no upstream"
+	}
+	return fmt.Sprintf("%s ↑%d ↓%d %s", label, b.Ahead, b.Behind, b.Upstream.Name)
+}
+
+// statusSortOrders are the orders the files can be listed in besides the order
+// of git, the files are kept in their sections and the items of the sub-lists
+// are kept as they are
+var statusSortOrders = []prompt.SortOrder{
+	{Name: "path", Less: func(a, b interface{}) bool {
+		return lessInSection(a, b, func(x, y *git.StatusEntry) bool {
+			return x.String() < y.String()
+		})
+	}},
+	{Name: "type", Less: func(a, b interface{}) bool {
+		return lessInSection(a, b, func(x, y *git.StatusEntry) bool {
+			return x.EntryType < y.EntryType
+		})
+	}},
+}
+
+// statusFilter narrows the files to the ones in a state, keep is nil for all
+// of the files
+type statusFilter struct {
+	name string
+	keep func(e *git.StatusEntry) bool
+}
+
+// statusFilters are cycled by the filter key
+var statusFilters = []statusFilter{
+	{"all", nil},
+	{"staged", (*git.StatusEntry).Indexed},
+	{"unstaged", func(e *git.StatusEntry) bool { return !e.Indexed() }},
+	{"conflicted", (*git.StatusEntry).Conflicted},
+}
+
+// statusHeader labels the files of a section, like the staged ones
+type statusHeader struct {
+	title string
+	state string // describes the files of the section
+	rank  int
+}
+
+func (h *statusHeader) String() string { return h.title }
+
+// Header makes the prompt skip the header while navigating
+func (h *statusHeader) Header() bool { return true }
+
+var statusHeaders = []*statusHeader{
+	{title: "Staged changes", state: "staged", rank: 0},
+	{title: "Unmerged paths", state: "conflicted", rank: 1},
+	{title: "Unstaged changes", state: "unstaged", rank: 2},
+	{title: "Untracked files", state: "untracked", rank: 3},
+	{title: "Ignored files", state: "ignored", rank: 4},
+}
+
+// statusRank groups the entries as staged, conflicted, unstaged, untracked and
+// ignored like git status does
+func statusRank(entry *git.StatusEntry) int {
+	switch {
+	case entry.Indexed():
+		return 0
+	case entry.Conflicted():
+		return 1
+	case entry.EntryType == git.StatusEntryTypeUntracked:
+		return 3
+	case entry.EntryType == git.StatusEntryTypeIgnored:
+		return 4
+	}
+	return 2
+}
+
+// statusItems groups the entries by their sections, each section is labeled by
+// a header if headers is set
+func statusItems(entries []*git.StatusEntry, headers bool) []interface{} {
+	sections := make([][]interface{}, len(statusHeaders))
+	for _, entry := range entries {
+		rank := statusRank(entry)
+		sections[rank] = append(sections[rank], entry)
+	}
+	items := make([]interface{}, 0, len(entries)+len(statusHeaders))
+	for rank, section := range sections {
+		if len(section) == 0 {
+			continue
+		}
+		if headers {
+			items = append(items, statusHeaders[rank])
+		}
+		items = append(items, section...)
+	}
+	return items
+}
+
+// lessInSection orders the headers and the entries by their sections, the
+// entries of a section are ordered by less
+func lessInSection(a, b interface{}, less func(x, y *git.StatusEntry) bool) bool {
+	x, xr := sectionOf(a)
+	y, yr := sectionOf(b)
+	switch {
+	case xr < 0 || yr < 0:
+		return false // not a status item
+	case xr != yr:
+		return xr < yr
+	case x == nil || y == nil:
+		return x == nil && y != nil // the header comes first
+	}
+	return less(x, y)
+}
+
+// sectionOf returns the entry and the rank of its section, entry is nil for a
+// header and the rank is -1 for the other items
+func sectionOf(item interface{}) (*git.StatusEntry, int) {
+	switch i := item.(type) {
+	case *git.StatusEntry:
+		return i, statusRank(i)
+	case *statusHeader:
+		return nil, i.rank
+	}
+	return nil, -1
+}
+
 // return err to terminate
 func (s *status) onSelect(item interface{}) error {
-	entry := item.(*git.StatusEntry)
-	if err := popGitCommand(s.repository, fileStatArgs(entry)); err != nil {
-		return nil // intentionally ignore errors here
+	switch item.(type) {
+	case *git.StatusEntry:
+		entry := item.(*git.StatusEntry)
+		if err := popGitCommand(s.repository, fileStatArgs(entry)); err != nil {
+			return nil // intentionally ignore errors here
+		}
+	case *hunk:
+		return s.applyHunks()
+	case *hunkLine:
+		s.hunk.selected = true
+		s.closeLines()
 	}
 	return nil
 }
 
 func (s *status) info(item interface{}) [][]term.Cell {
+	if h, ok := item.(*hunk); ok {
+		return hunkLines(h)
+	}
+	if _, ok := item.(*hunkLine); ok {
+		return hunkLines(s.hunk)
+	}
+	if _, ok := item.(*splitRow); ok {
+		return nil
+	}
 	b := s.repository.Head
-	return branchInfo(b, true)
+	grid := append(stateInfo(s.state), branchInfo(b, true)...)
+	if entry, ok := item.(*git.StatusEntry); ok {
+		grid = append(grid, s.entryInfo(entry)...)
+	}
+	return append(grid, s.dry.info()...)
+}
+
+// entryInfo tells whether the file is staged, unstaged or both, with the
+// diffstat of each of its changes
+func (s *status) entryInfo(entry *git.StatusEntry) [][]term.Cell {
+	var sides []*git.StatusEntry
+	for _, e := range s.entries {
+		if e.Identity() == entry.Identity() {
+			sides = append(sides, e)
+		}
+	}
+	if len(sides) == 0 {
+		sides = append(sides, entry)
+	}
+	sort.SliceStable(sides, func(i, j int) bool { return statusRank(sides[i]) < statusRank(sides[j]) })
+	states := make([]string, len(sides))
+	for i, e := range sides {
+		states[i] = statusHeaders[statusRank(e)].state
+	}
+	line := term.Cprint(entry.String(), color.FgWhite)
+	line = append(line, term.Cprint(" is "+strings.Join(states, " and "), color.Faint)...)
+	grid := [][]term.Cell{line}
+	for i, e := range sides {
+		if e.Conflicted() {
+			continue
+		}
+		if stat := s.diffStat(e); stat != nil {
+			grid = append(grid, statLine(states[i], stat.added, stat.deleted))
+		}
+	}
+	return grid
 }
 
 func (s *status) defineKeybindings() error {
 	keybindings := []*prompt.KeyBinding{
 		&prompt.KeyBinding{
-			Key:     ' ',
-			Display: "space",
-			Desc:    "add/reset entry",
-			Handler: s.addResetEntry,
+			Key:      ' ',
+			Display:  "space",
+			Desc:     "add/reset entry",
+			Category: "Staging",
+			Primary:  true,
+			Handler:  s.addResetEntry,
+			DescFunc: addResetDesc,
+		},
+		&prompt.KeyBinding{
+			Key:      'p',
+			Display:  "p",
+			Desc:     "hunk stage entry",
+			Category: "Staging",
+			Handler:  s.hunkStageEntry,
+			Enabled:  resolvedEntry,
+		},
+		&prompt.KeyBinding{
+			Key:      'P',
+			Display:  "P",
+			Desc:     "select hunks to stage",
+			Category: "Staging",
+			Handler:  s.selectHunks,
+			Enabled:  diffEntry,
+		},
+		&prompt.KeyBinding{
+			Key:      'L',
+			Display:  "L",
+			Desc:     "select lines of hunk",
+			Category: "Staging",
+			Handler:  s.selectLines,
+			Enabled:  isHunk,
+		},
+		&prompt.KeyBinding{
+			Key:      's',
+			Display:  "s",
+			Desc:     "split diff",
+			Category: "Diff",
+			Handler:  s.splitView,
+			Enabled:  diffEntry,
+		},
+		&prompt.KeyBinding{
+			Key:      'w',
+			Display:  "w",
+			Desc:     "toggle word diff",
+			Category: "Diff",
+			Handler:  s.toggleWordDiff,
+			Enabled:  isSplitRow,
+		},
+		&prompt.KeyBinding{
+			Key:      'c',
+			Display:  "c",
+			Desc:     "commit",
+			Category: "Commit",
+			Primary:  true,
+			Handler:  s.commit,
+			Enabled:  s.listingFiles,
+		},
+		&prompt.KeyBinding{
+			Key:      'm',
+			Display:  "m",
+			Desc:     "amend",
+			Category: "Commit",
+			Handler:  s.amend,
+			Enabled:  s.listingFiles,
+		},
+		&prompt.KeyBinding{
+			Key:      'a',
+			Display:  "a",
+			Desc:     "add all",
+			Category: "Staging",
+			Primary:  true,
+			Handler:  s.addAllEntries,
+			Enabled:  s.listingFiles,
+		},
+		&prompt.KeyBinding{
+			Key:      'r',
+			Display:  "r",
+			Desc:     "reset all",
+			Category: "Staging",
+			Handler:  s.resetAllEntries,
+			Enabled:  s.listingFiles,
 		},
 		&prompt.KeyBinding{
-			Key:     'p',
-			Display: "p",
-			Desc:    "hunk stage entry",
-			Handler: s.hunkStageEntry,
+			Key:      'e',
+			Display:  "e",
+			Desc:     "edit conflicted file",
+			Category: "Conflicts",
+			Handler:  s.editConflict,
+			Enabled:  conflictedEntry,
 		},
 		&prompt.KeyBinding{
-			Key:     'c',
-			Display: "c",
-			Desc:    "commit",
-			Handler: s.commit,
+			Key:      '<',
+			Display:  "<",
+			Desc:     "resolve with ours",
+			Category: "Conflicts",
+			Handler:  s.resolveOurs,
+			Enabled:  conflictedEntry,
 		},
 		&prompt.KeyBinding{
-			Key:     'm',
-			Display: "m",
-			Desc:    "amend",
-			Handler: s.amend,
+			Key:      '>',
+			Display:  ">",
+			Desc:     "resolve with theirs",
+			Category: "Conflicts",
+			Handler:  s.resolveTheirs,
+			Enabled:  conflictedEntry,
 		},
 		&prompt.KeyBinding{
-			Key:     'a',
-			Display: "a",
-			Desc:    "add all",
-			Handler: s.addAllEntries,
+			Key:      'f',
+			Display:  "f",
+			Desc:     "filter files (all/staged/unstaged/conflicted)",
+			Category: "Files",
+			Handler:  s.cycleFilter,
+			Enabled:  isEntry,
 		},
 		&prompt.KeyBinding{
-			Key:     'r',
-			Display: "r",
-			Desc:    "reset all",
-			Handler: s.resetAllEntries,
+			Key:      'u',
+			Display:  "u",
+			Desc:     "show/hide untracked files",
+			Category: "Files",
+			Handler:  s.toggleUntracked,
+			Enabled:  isEntry,
 		},
 		&prompt.KeyBinding{
-			Key:     '!',
-			Display: "!",
-			Desc:    "discard changes",
-			Handler: s.discardEntry,
+			Key:      'i',
+			Display:  "i",
+			Desc:     "show/hide ignored files",
+			Category: "Files",
+			Handler:  s.toggleIgnored,
+			Enabled:  isEntry,
 		},
 		&prompt.KeyBinding{
-			Key:     'q',
-			Display: "q",
-			Desc:    "quit",
-			Handler: s.quit,
+			Key:      '!',
+			Display:  "!",
+			Desc:     "discard changes",
+			Category: "Staging",
+			Handler:  s.discardEntry,
+			Enabled:  unstagedEntry,
 		},
+		copyBinding(s.prompt),
 	}
 	for _, kb := range keybindings {
 		if err := s.prompt.AddKeyBinding(kb); err != nil {
@@ -126,16 +431,38 @@ func (s *status) defineKeybindings() error {
 }
 
 func (s *status) addResetEntry(item interface{}) error {
-	entry := item.(*git.StatusEntry)
-	args := []string{"add", "--", entry.String()}
+	switch i := item.(type) {
+	case *hunk:
+		i.selected = !i.selected
+		return nil
+	case *hunkLine:
+		i.selected = i.changed() && !i.selected
+		return nil
+	}
+	entry, ok := item.(*git.StatusEntry)
+	if !ok {
+		return nil
+	}
+	args, msg := []string{"add", "--", entry.String()}, "Staged "
 	if entry.Indexed() {
-		args = []string{"reset", "HEAD", "--", entry.String()}
+		args, msg = []string{"reset", "HEAD", "--", entry.String()}, "Unstaged "
 	}
-	return s.runCommandWithArgs(args)
+	if s.dry.skip(s.prompt, args) {
+		return nil
+	}
+	if err := runGitCommand(s.repository, args); err != nil {
+		showError(s.prompt, err)
+		return nil
+	}
+	showMessage(s.prompt, msg+entry.String())
+	return s.reloadStatus()
 }
 
 func (s *status) hunkStageEntry(item interface{}) error {
-	entry := item.(*git.StatusEntry)
+	entry, ok := item.(*git.StatusEntry)
+	if !ok || entry.Conflicted() {
+		return nil
+	}
 	file, err := generateDiffFile(s.repository, entry)
 	if err == nil {
 		editor, err := editor.NewEditor(file)
@@ -147,6 +474,9 @@ func (s *status) hunkStageEntry(item interface{}) error {
 			return err
 		}
 		for _, patch := range patches {
+			if s.dry.skip(s.prompt, applyPatchArgs(entry)) {
+				return nil
+			}
 			if err := applyPatchCmd(s.repository, entry, patch); err != nil {
 				return err
 			}
@@ -155,6 +485,132 @@ func (s *status) hunkStageEntry(item interface{}) error {
 	return s.reloadStatus()
 }
 
+// selectHunks replaces the list with the hunks of the entry, they are toggled
+// with space and the selected ones are applied with enter
+func (s *status) selectHunks(item interface{}) error {
+	entry, ok := item.(*git.StatusEntry)
+	if !ok || entry.Untracked() || entry.Conflicted() {
+		return nil
+	}
+	file, err := generateDiffFile(s.repository, entry)
+	if err != nil || len(file.Hunks) == 0 {
+		return nil // e.g. a binary file
+	}
+	hunks := newHunks(file)
+	list, err := prompt.NewList(hunks, s.prompt.ListSize())
+	if err != nil {
+		return err
+	}
+	s.entry = entry
+	s.file = file
+	s.hunks = hunks
+	s.oldState = s.prompt.State()
+	s.prompt.SetState(&prompt.State{
+		List:        list,
+		SearchLabel: "Hunks of " + entry.String(),
+	})
+	return nil
+}
+
+// applyHunks stages the selected hunks, or unstages them if the entry is
+// already indexed, and returns to the file list
+func (s *status) applyHunks() error {
+	if patch := hunksPatch(s.file, s.hunks, s.entry.Indexed()); len(patch) > 0 && !s.dry.skip(s.prompt, applyPatchArgs(s.entry)) {
+		if err := applyPatchCmd(s.repository, s.entry, patch); err != nil {
+			// the patch might not apply anymore, the hunks are listed as they are now
+			showError(s.prompt, err)
+			return s.reloadHunks()
+		}
+	}
+	s.prompt.SetState(s.oldState)
+	s.entry, s.file, s.hunks, s.oldState = nil, nil, nil, nil
+	return s.reloadStatus()
+}
+
+// reloadHunks lists the hunks of the entry again, or the files if it has no
+// hunks anymore
+func (s *status) reloadHunks() error {
+	file, err := generateDiffFile(s.repository, s.entry)
+	if err != nil || len(file.Hunks) == 0 {
+		s.prompt.SetState(s.oldState)
+		s.entry, s.file, s.hunks, s.oldState = nil, nil, nil, nil
+		return s.reloadStatus()
+	}
+	hunks := newHunks(file)
+	list, err := prompt.NewList(hunks, s.prompt.ListSize())
+	if err != nil {
+		return err
+	}
+	s.file, s.hunks = file, hunks
+	state := s.prompt.State()
+	state.List = list
+	s.prompt.SetState(state)
+	return nil
+}
+
+// splitView replaces the list with the side-by-side diff of the entry
+func (s *status) splitView(item interface{}) error {
+	entry, ok := item.(*git.StatusEntry)
+	if !ok || entry.Untracked() || entry.Conflicted() {
+		return nil
+	}
+	file, err := generateDiffFile(s.repository, entry)
+	if err != nil || len(file.Hunks) == 0 {
+		return nil
+	}
+	s.split = file
+	s.oldState = s.prompt.State()
+	return s.renderSplit(&prompt.State{SearchLabel: "Diff of " + entry.String()})
+}
+
+// toggleWordDiff switches between highlighting the changed lines and only the
+// changed words of them in the side-by-side diff
+func (s *status) toggleWordDiff(item interface{}) error {
+	s.wordDiff = !s.wordDiff
+	return s.renderSplit(s.prompt.State())
+}
+
+func (s *status) renderSplit(state *prompt.State) error {
+	width, _, err := term.Size()
+	if err != nil {
+		return nil
+	}
+	list, err := prompt.NewList(splitDiff(s.split, width, s.wordDiff), s.prompt.ListSize())
+	if err != nil {
+		return err
+	}
+	state.List = list
+	s.prompt.SetState(state)
+	return nil
+}
+
+// selectLines replaces the hunk list with the lines of the hunk so that the
+// changes can be toggled one by one, enter selects the hunk with the remaining
+// changes
+func (s *status) selectLines(item interface{}) error {
+	h, ok := item.(*hunk)
+	if !ok {
+		return nil
+	}
+	list, err := prompt.NewList(h.lines, s.prompt.ListSize())
+	if err != nil {
+		return err
+	}
+	s.hunk = h
+	s.hunkState = s.prompt.State()
+	s.prompt.SetState(&prompt.State{
+		List:        list,
+		SearchLabel: "Lines of " + s.entry.String(),
+	})
+	return nil
+}
+
+// closeLines returns to the hunk list
+func (s *status) closeLines() {
+	s.prompt.SetState(s.hunkState)
+	s.hunk, s.hunkState = nil, nil
+}
+
 func (s *status) commit(item interface{}) error {
 	s.bareCommit("--edit") // why ignore err? simply to return status screen
 	return nil
@@ -166,7 +622,10 @@ func (s *status) amend(item interface{}) error {
 }
 
 func (s *status) bareCommit(arg string) error {
-	args := []string{"commit", arg, "--quiet"}
+	args := append([]string{"commit", arg, "--quiet"}, commitConfigArgs(s.repository.ConfigString, arg == "--amend")...)
+	if s.dry.skip(s.prompt, args) {
+		return nil
+	}
 	err := popGitCommand(s.repository, args)
 	if err != nil {
 		return err
@@ -193,34 +652,224 @@ func (s *status) resetAllEntries(item interface{}) error {
 }
 
 func (s *status) discardEntry(item interface{}) error {
-	entry := item.(*git.StatusEntry)
+	entry, ok := item.(*git.StatusEntry)
+	if !ok {
+		return nil
+	}
+	if ok, err := s.prompt.Confirm("Discard changes of " + entry.String() + "?"); !ok {
+		return err
+	}
 	var args []string
 	if entry.EntryType == git.StatusEntryTypeUntracked {
 		args = []string{"clean", "--force", entry.String()}
+	} else if entry.EntryType == git.StatusEntryTypeIgnored {
+		args = []string{"clean", "--force", "-x", entry.String()}
 	} else {
 		args = []string{"checkout", "--", entry.String()}
 	}
 	return s.runCommandWithArgs(args)
 }
 
+// editConflict opens the conflicted file in the editor of git, the file is
+// marked as resolved by staging it once the markers are removed
+func (s *status) editConflict(item interface{}) error {
+	entry, ok := item.(*git.StatusEntry)
+	if !ok || !entry.Conflicted() {
+		return nil
+	}
+	if err := editFile(s.repository, entry.String()); err != nil {
+		showError(s.prompt, err)
+		return nil
+	}
+	showMessage(s.prompt, "Press space to mark "+entry.String()+" as resolved")
+	return s.reloadStatus()
+}
+
+func (s *status) resolveOurs(item interface{}) error {
+	return s.resolveConflict(item, "--ours")
+}
+
+func (s *status) resolveTheirs(item interface{}) error {
+	return s.resolveConflict(item, "--theirs")
+}
+
+// resolveConflict checks out a side of the conflicted file and stages it. Note
+// that the sides are swapped while rebasing, ours is the branch that is rebased
+// onto.
+func (s *status) resolveConflict(item interface{}, side string) error {
+	entry, ok := item.(*git.StatusEntry)
+	if !ok || !entry.Conflicted() {
+		return nil
+	}
+	for _, args := range [][]string{
+		{"checkout", side, "--", entry.String()},
+		{"add", "--", entry.String()},
+	} {
+		if s.dry.skip(s.prompt, args) {
+			return nil
+		}
+		if err := runGitCommand(s.repository, args); err != nil {
+			showError(s.prompt, err)
+			return nil
+		}
+	}
+	showMessage(s.prompt, "Resolved "+entry.String()+" with "+strings.TrimPrefix(side, "--"))
+	return s.reloadStatus()
+}
+
 func (s *status) quit(item interface{}) error {
+	if back, err := s.back(); back || err != nil {
+		return err
+	}
 	s.prompt.Stop()
 	return nil
 }
 
+// back lists what was listed before the displayed sub-list, it is decided by
+// the state rather than the selected item since nothing may match the search.
+// It returns false if the files are listed.
+func (s *status) back() (bool, error) {
+	switch {
+	case s.hunkState != nil:
+		s.closeLines()
+		return true, nil
+	case s.split != nil:
+		s.prompt.SetState(s.oldState)
+		s.split, s.oldState = nil, nil
+		return true, s.reloadStale()
+	case s.oldState != nil:
+		s.prompt.SetState(s.oldState)
+		s.entry, s.file, s.hunks, s.oldState = nil, nil, nil, nil
+		return true, s.reloadStale()
+	}
+	return false, nil
+}
+
 func (s *status) runCommandWithArgs(args []string) error {
-	cmd := exec.Command("git", args...)
-	cmd.Dir = s.repository.Path()
-	if err := cmd.Run(); err != nil {
-		return nil //ignore command errors for now
+	if s.dry.skip(s.prompt, args) {
+		return nil
+	}
+	if err := runGitCommand(s.repository, args); err != nil {
+		showError(s.prompt, err)
+		return nil
+	}
+	return s.reloadStatus()
+}
+
+// reloadStale reloads the list if the working tree has changed while it wasn't
+// displayed
+func (s *status) reloadStale() error {
+	if !s.stale {
+		return nil
 	}
 	return s.reloadStatus()
 }
 
+// addResetDesc tells what space does to the item in the hints
+func addResetDesc(item interface{}) string {
+	switch i := item.(type) {
+	case *git.StatusEntry:
+		if i.Indexed() {
+			return "reset"
+		}
+		return "add"
+	case *hunk, *hunkLine:
+		return "select"
+	}
+	return "add/reset entry"
+}
+
+// resolvedEntry enables the bindings for the files without a conflict
+func resolvedEntry(item interface{}) bool {
+	entry, ok := item.(*git.StatusEntry)
+	return ok && !entry.Conflicted()
+}
+
+// diffEntry enables the bindings for the files with a diff to show
+func diffEntry(item interface{}) bool {
+	return resolvedEntry(item) && !item.(*git.StatusEntry).Untracked()
+}
+
+// conflictedEntry enables the bindings for the files with a conflict
+func conflictedEntry(item interface{}) bool {
+	entry, ok := item.(*git.StatusEntry)
+	return ok && entry.Conflicted()
+}
+
+// unstagedEntry enables the bindings for the changes of the working tree
+func unstagedEntry(item interface{}) bool {
+	return resolvedEntry(item) && !item.(*git.StatusEntry).Indexed()
+}
+
+// isEntry enables the bindings for the list of the files
+func isEntry(item interface{}) bool {
+	_, ok := item.(*git.StatusEntry)
+	return ok
+}
+
+// isHunk enables the bindings for the hunks of a file
+func isHunk(item interface{}) bool {
+	_, ok := item.(*hunk)
+	return ok
+}
+
+// isSplitRow enables the bindings for the side-by-side diff
+func isSplitRow(item interface{}) bool {
+	_, ok := item.(*splitRow)
+	return ok
+}
+
+// listingFiles enables the bindings for the whole working tree, they are
+// disabled while the hunks, the lines or the split diff of a file are listed
+func (s *status) listingFiles(item interface{}) bool {
+	return s.oldState == nil
+}
+
+// cycleFilter lists the files of the next filter, the filters without a file
+// are skipped so that the list is never empty. The items of the sub-lists are
+// not filtered.
+func (s *status) cycleFilter(item interface{}) error {
+	s.filter = (s.filter + 1) % len(statusFilters)
+	for !s.filterMatches() {
+		s.filter = (s.filter + 1) % len(statusFilters)
+	}
+	s.applyFilter()
+	showMessage(s.prompt, "Listing "+statusFilters[s.filter].name+" files")
+	return nil
+}
+
+// filterMatches returns true if any file passes the applied filter
+func (s *status) filterMatches() bool {
+	keep := statusFilters[s.filter].keep
+	if keep == nil {
+		return true
+	}
+	for _, entry := range s.entries {
+		if keep(entry) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyFilter narrows the list to the files of the applied filter
+func (s *status) applyFilter() {
+	f := statusFilters[s.filter]
+	if f.keep == nil {
+		s.prompt.Filter(nil)
+	} else {
+		s.prompt.Filter(func(item interface{}) bool {
+			entry, ok := item.(*git.StatusEntry)
+			return !ok || f.keep(entry)
+		})
+	}
+}
+
 // reloads the list
 func (s *status) reloadStatus() error {
+	s.stale = false
 	s.repository.LoadHead()
-	status, err := s.repository.LoadStatus()
+	status, err := s.repository.LoadStatus(&s.show)
 	if err != nil {
 		return err
 	}
@@ -230,8 +879,51 @@ func (s *status) reloadStatus() error {
 		s.prompt.SetExitMsg(workingTreeClean(s.repository.Head))
 		return nil
 	}
+	return s.listStatus(status)
+}
+
+// toggleUntracked lists or hides the untracked files
+func (s *status) toggleUntracked(item interface{}) error {
+	return s.toggleShown(&s.show.Untracked, "untracked")
+}
+
+// toggleIgnored lists or hides the ignored files
+func (s *status) toggleIgnored(item interface{}) error {
+	return s.toggleShown(&s.show.Ignored, "ignored")
+}
+
+// toggleShown flips one of the status options and lists the files again, the
+// option is kept if no file would be left to list
+func (s *status) toggleShown(shown *bool, kind string) error {
+	*shown = !*shown
+	status, err := s.repository.LoadStatus(&s.show)
+	if err != nil || len(status.Entities) == 0 {
+		*shown = !*shown
+		if err == nil {
+			showMessage(s.prompt, "No other files to list")
+		}
+		return err
+	}
+	if *shown {
+		showMessage(s.prompt, "Listing "+kind+" files")
+	} else {
+		showMessage(s.prompt, "Hiding "+kind+" files")
+	}
+	return s.listStatus(status)
+}
+
+// listStatus replaces the list with the files of the status
+func (s *status) listStatus(status *git.Status) error {
+	s.state = status.State
+	s.entries = status.Entities
+	s.stats = make(map[*git.StatusEntry]*lineStat)
+	if !s.filterMatches() {
+		s.filter = 0
+		s.applyFilter()
+	}
 	state := s.prompt.State()
-	list, err := prompt.NewList(status.Entities, state.ListSize)
+	state.SearchLabel = statusLabel(s.repository.Head)
+	list, err := prompt.NewList(statusItems(status.Entities, s.headers), state.ListSize)
 	if err != nil {
 		return err
 	}
@@ -240,12 +932,56 @@ func (s *status) reloadStatus() error {
 	return nil
 }
 
+// lineStat holds the numbers of the added and the deleted lines of a change,
+// they are "-" for a binary file
+type lineStat struct {
+	added, deleted string
+}
+
+// diffStat returns the numbers of the added and the deleted lines of the entry,
+// or nil if it has none. They are cached until the status is reloaded since
+// the information is rendered on each key press.
+func (s *status) diffStat(e *git.StatusEntry) *lineStat {
+	if stat, ok := s.stats[e]; ok {
+		return stat
+	}
+	// the error is ignored since diff --no-index exits with 1 if there is a diff
+	out, _ := gitCommand(s.repository, fileStatArgs(e)...).Output()
+	stat := parseLineStat(string(out))
+	s.stats[e] = stat
+	return stat
+}
+
+// parseLineStat counts the added and the deleted lines of the hunks of a
+// diff, it returns nil if the diff has no changes
+func parseLineStat(out string) *lineStat {
+	if strings.Contains(out, "\nBinary files ") {
+		return &lineStat{added: "-", deleted: "-"}
+	}
+	diff, err := diffparser.Parse(out)
+	if err != nil || len(diff.Files) == 0 || len(diff.Files[0].Hunks) == 0 {
+		return nil
+	}
+	var added, deleted int
+	for _, h := range diff.Files[0].Hunks {
+		for _, l := range h.WholeRange.Lines {
+			switch l.Mode {
+			case diffparser.ADDED:
+				added++
+			case diffparser.REMOVED:
+				deleted++
+			}
+		}
+	}
+	return &lineStat{added: strconv.Itoa(added), deleted: strconv.Itoa(deleted)}
+}
+
 // fileStatArgs returns git command args for getting diff
 func fileStatArgs(e *git.StatusEntry) []string {
 	var args []string
 	if e.Indexed() {
 		args = []string{"diff", "--cached", e.String()}
-	} else if e.EntryType == git.StatusEntryTypeUntracked {
+	} else if e.Untracked() {
 		args = []string{"diff", "--no-index", "/dev/null", e.String()}
 	} else {
 		args = []string{"diff", "--", e.String()}
@@ -253,9 +989,42 @@ func fileStatArgs(e *git.StatusEntry) []string {
 	return args
 }
 
+// commitConfigArgs returns the args for signing the commit and preloading the
+// message template as configured in commit.gpgsign and commit.template, the
+// config is read with lookup
+func commitConfigArgs(lookup func(name string) (string, error), amend bool) []string {
+	var args []string
+	if sign, err := lookup("commit.gpgsign"); err == nil && configTrue(sign) {
+		if key, err := lookup("user.signingkey"); err == nil && len(key) > 0 {
+			args = append(args, "--gpg-sign="+key)
+		} else {
+			args = append(args, "--gpg-sign")
+		}
+	}
+	// an amend starts with the message of the commit instead of the template
+	if template, err := lookup("commit.template"); err == nil && len(template) > 0 && !amend {
+		if strings.HasPrefix(template, "~/") {
+			if home, err := os.UserHomeDir(); err == nil {
+				template = filepath.Join(home, template[2:])
+			}
+		}
+		args = append(args, "--template="+template)
+	}
+	return args
+}
+
+// configTrue returns true if the config value is one of the true values of git
+func configTrue(value string) bool {
+	switch strings.ToLower(value) {
+	case "true", "yes", "on", "1":
+		return true
+	}
+	return false
+}
+
 // lastCommitArgs returns the args for show stat
 func lastCommitArgs(r *git.Repository) ([]string, error) {
-	r.LoadStatus()
+	r.LoadStatus(nil)
 	head := r.Head
 	if head == nil {
 		return nil, fmt.Errorf("can't get HEAD")
@@ -267,8 +1036,7 @@ func lastCommitArgs(r *git.Repository) ([]string, error) {
 
 func generateDiffFile(r *git.Repository, entry *git.StatusEntry) (*diffparser.DiffFile, error) {
 	args := fileStatArgs(entry)
-	cmd := exec.Command("git", args...)
-	cmd.Dir = r.Path()
+	cmd := gitCommand(r, args...)
 	out, err := cmd.CombinedOutput()
 	if err != nil {
 		return nil, err
@@ -280,13 +1048,15 @@ func generateDiffFile(r *git.Repository, entry *git.StatusEntry) (*diffparser.Di
 	return diff.Files[0], nil
 }
 
-func applyPatchCmd(r *git.Repository, entry *git.StatusEntry, patch string) error {
-	mode := []string{"apply", "--cached"}
+func applyPatchArgs(entry *git.StatusEntry) []string {
 	if entry.Indexed() {
-		mode = []string{"apply", "--cached", "--reverse"}
+		return []string{"apply", "--cached", "--reverse"}
 	}
-	cmd := exec.Command("git", mode...)
-	cmd.Dir = r.Path()
+	return []string{"apply", "--cached"}
+}
+
+func applyPatchCmd(r *git.Repository, entry *git.StatusEntry, patch string) error {
+	cmd := gitCommand(r, applyPatchArgs(entry)...)
 	stdin, err := cmd.StdinPipe()
 	if err != nil {
 		return err
@@ -295,8 +1065,8 @@ func applyPatchCmd(r *git.Repository, entry *git.StatusEntry, patch string) erro
 		defer stdin.Close()
 		io.WriteString(stdin, patch+"\n")
 	}()
-	if err := cmd.Run(); err != nil {
-		return err
-	}
-	return nil
+	var out bytes.Buffer
+	cmd.Stderr = &out
+	err = cmd.Run()
+	return commandError(out.Bytes(), err)
 }
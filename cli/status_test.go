@@ -0,0 +1,93 @@
+package cli
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/isacikgoz/gitin/prompt"
+)
+
+func TestParseLineStat(t *testing.T) {
+	var tests = []struct {
+		diff string
+		want *lineStat
+	}{
+		{"", nil},
+		{"diff --git a/f b/f\nindex 1..2 100644\n--- a/f\n+++ b/f\n@@ -1,2 +1,3 @@\n a\n-b\n+c\n+d\n", &lineStat{"2", "1"}},
+		{"diff --git a/f b/f\nnew file mode 100644\nindex 0..1\n--- /dev/null\n+++ b/f\n@@ -0,0 +1,2 @@\n+a\n+b\n\\ No newline at end of file\n", &lineStat{"2", "0"}},
+		{"diff --git a/bin b/bin\nnew file mode 100644\nindex 0..1\nBinary files /dev/null and b/bin differ\n", &lineStat{"-", "-"}},
+		{"diff --git a/f b/f\nold mode 100644\nnew mode 100755\n", nil},
+	}
+	for _, test := range tests {
+		got := parseLineStat(test.diff)
+		if (got == nil) != (test.want == nil) || (got != nil && *got != *test.want) {
+			t.Errorf("got %v for %q, want %v", got, test.diff, test.want)
+		}
+	}
+}
+
+func TestCommitConfigArgs(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tests = []struct {
+		config map[string]string
+		amend  bool
+		want   []string
+	}{
+		{map[string]string{}, false, nil},
+		{map[string]string{"commit.gpgsign": "false", "user.signingkey": "ABC"}, false, nil},
+		{map[string]string{"commit.gpgsign": "true"}, false, []string{"--gpg-sign"}},
+		{map[string]string{"commit.gpgsign": "yes", "user.signingkey": "ABC"}, false, []string{"--gpg-sign=ABC"}},
+		{map[string]string{"commit.template": "/tmp/msg"}, false, []string{"--template=/tmp/msg"}},
+		{map[string]string{"commit.template": "~/msg"}, false, []string{"--template=" + filepath.Join(home, "msg")}},
+		{map[string]string{"commit.gpgsign": "on", "commit.template": "/tmp/msg"}, true, []string{"--gpg-sign"}},
+	}
+	for _, test := range tests {
+		lookup := func(name string) (string, error) {
+			if value, ok := test.config[name]; ok {
+				return value, nil
+			}
+			return "", errors.New("config value not found")
+		}
+		got := commitConfigArgs(lookup, test.amend)
+		if strings.Join(got, " ") != strings.Join(test.want, " ") {
+			t.Errorf("config: %v amend: %v\n got: %q want: %q", test.config, test.amend, got, test.want)
+		}
+	}
+}
+
+func TestQuitSubListWithoutMatches(t *testing.T) {
+	files, err := prompt.NewList([]string{"a.go", "b.go"}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hunks, err := prompt.NewList([]string{"@@ -1 +1 @@", "@@ -5 +5 @@"}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &status{}
+	s.prompt = prompt.Create("Files", &prompt.Options{}, files)
+	s.oldState = s.prompt.State()
+	state := s.prompt.State()
+	hunks.Search("nothing")
+	state.List, state.SearchStr = hunks, "nothing"
+	s.prompt.SetState(state)
+	if matched := s.prompt.State().Matched; matched != 0 {
+		t.Fatalf("got %d matching hunks, want none", matched)
+	}
+
+	if err := s.quit(nil); err != nil {
+		t.Fatal(err)
+	}
+	if s.oldState != nil || s.prompt.State().List != files {
+		t.Errorf("q with no matches should list the files again")
+	}
+	if back, _ := s.back(); back {
+		t.Errorf("the files should be the top level")
+	}
+}
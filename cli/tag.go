@@ -0,0 +1,183 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/isacikgoz/gitin/git"
+	"github.com/isacikgoz/gitin/prompt"
+	"github.com/isacikgoz/gitin/term"
+	"github.com/justincampbell/timeago"
+)
+
+// tag holds the repository struct and the prompt pointer.
+type tag struct {
+	repository *git.Repository
+	prompt     *prompt.Prompt
+}
+
+// TagPrompt configures a prompt to serve as a tag prompt
+func TagPrompt(r *git.Repository, opts *Options) (*prompt.Prompt, error) {
+	tags, err := r.Tags()
+	if err != nil {
+		return nil, fmt.Errorf("could not load tags: %v", err)
+	}
+	if len(tags) == 0 {
+		return nil, &EmptyError{Message: noTags()}
+	}
+	list, err := prompt.NewList(tags, lineSize(opts))
+	if err != nil {
+		return nil, fmt.Errorf("could not create list: %v", err)
+	}
+
+	t := &tag{repository: r}
+	t.prompt = prompt.Create("Tags", &opts.Options, list,
+		prompt.WithSelectionHandler(t.onSelect),
+		prompt.WithItemRenderer(itemRenderer(opts)),
+		prompt.WithEmptyMessage(noMatches("tags")),
+		prompt.WithInformation(t.info),
+	)
+	if err := t.defineKeybindings(opts); err != nil {
+		return nil, err
+	}
+
+	return t.prompt, nil
+}
+
+func (t *tag) onSelect(item interface{}) error {
+	entry := item.(*git.Tag)
+	return popGitCommand(t.repository, []string{"show", entry.Name})
+}
+
+func (t *tag) info(item interface{}) [][]term.Cell {
+	entry := item.(*git.Tag)
+	grid := make([][]term.Cell, 0)
+	if commit := entry.Target(); commit != nil {
+		cells := term.Cprint("Tags ", color.Faint)
+		cells = append(cells, term.Cprint(commit.Hash[:7]+" ", color.FgCyan)...)
+		cells = append(cells, term.Cprint(commit.Summary, color.FgWhite)...)
+		grid = append(grid, cells)
+	}
+	if !entry.Annotated {
+		return grid
+	}
+	if entry.Tagger != nil {
+		cells := term.Cprint("Tagged by ", color.Faint)
+		cells = append(cells, term.Cprint(entry.Tagger.Name, color.FgYellow)...)
+		cells = append(cells, term.Cprint(" "+timeago.FromTime(entry.Tagger.When), color.FgBlue)...)
+		grid = append(grid, cells)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(entry.Message), "\n") {
+		grid = append(grid, term.Cprint(line, color.Faint))
+	}
+	return grid
+}
+
+func (t *tag) defineKeybindings(opts *Options) error {
+	keybindings := []*prompt.KeyBinding{
+		&prompt.KeyBinding{
+			Key:     't',
+			Display: "t",
+			Desc:    "create tag",
+			Primary: true,
+			Handler: t.createTag,
+		},
+		&prompt.KeyBinding{
+			Key:     'T',
+			Display: "T",
+			Desc:    "create annotated tag",
+			Handler: t.createAnnotatedTag,
+		},
+		deleteBinding(opts, "delete tag", t.deleteTag),
+		&prompt.KeyBinding{
+			Key:     'P',
+			Display: "P",
+			Desc:    "push tag",
+			Primary: true,
+			Handler: t.pushTag,
+		},
+		copyBinding(t.prompt),
+	}
+	for _, kb := range keybindings {
+		if err := t.prompt.AddKeyBinding(kb); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createTag creates a lightweight tag on HEAD
+func (t *tag) createTag(item interface{}) error {
+	name, err := t.prompt.Input("New tag at HEAD", "")
+	if err != nil || len(name) == 0 {
+		return err
+	}
+	return t.runCommandWithArgs([]string{"tag", name})
+}
+
+// createAnnotatedTag creates a tag on HEAD, the message is written with the
+// editor
+func (t *tag) createAnnotatedTag(item interface{}) error {
+	name, err := t.prompt.Input("New annotated tag at HEAD", "")
+	if err != nil || len(name) == 0 {
+		return err
+	}
+	if err := popGitCommand(t.repository, []string{"tag", "--annotate", name}); err != nil {
+		return nil // possibly an invalid or existing name, or an empty message
+	}
+	return t.reloadTags()
+}
+
+func (t *tag) deleteTag(item interface{}) error {
+	entry := item.(*git.Tag)
+	if ok, err := t.prompt.Confirm("Delete tag " + entry.Shorthand + "?"); !ok {
+		return err
+	}
+	return t.runCommandWithArgs([]string{"tag", "--delete", entry.Shorthand})
+}
+
+// pushTag pushes the tag to origin, the git command is attached to the
+// terminal in case it asks for credentials
+func (t *tag) pushTag(item interface{}) error {
+	entry := item.(*git.Tag)
+	if ok, err := t.prompt.Confirm("Push tag " + entry.Shorthand + " to origin?"); !ok {
+		return err
+	}
+	if err := popGitCommand(t.repository, []string{"push", "origin", entry.Name}); err != nil {
+		showError(t.prompt, err)
+		return nil
+	}
+	showMessage(t.prompt, "Pushed tag "+entry.Shorthand)
+	return nil
+}
+
+func (t *tag) runCommandWithArgs(args []string) error {
+	if err := runGitCommand(t.repository, args); err != nil {
+		showError(t.prompt, err) // possibly an invalid or existing name
+		return nil
+	}
+	return t.reloadTags()
+}
+
+// reloads the list
+func (t *tag) reloadTags() error {
+	tags, err := t.repository.Tags()
+	if err != nil {
+		return err
+	}
+	if len(tags) == 0 {
+		// this is the case when the last tag is deleted at runtime
+		t.prompt.Stop()
+		t.prompt.SetExitMsg(noTags())
+		return nil
+	}
+	state := t.prompt.State()
+	list, err := prompt.NewList(tags, state.ListSize)
+	if err != nil {
+		return err
+	}
+	state.List = list
+	t.prompt.SetState(state)
+	return nil
+}
@@ -0,0 +1,163 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watch returns a job that reloads the status once the working tree or the
+// index has changed. The changes must settle for the interval, so that a burst
+// of changes like a checkout triggers a single reload.
+func (s *status) watch(interval time.Duration) func(ctx context.Context) {
+	return func(ctx context.Context) {
+		watcher, err := fsnotify.NewWatcher()
+		if err != nil {
+			return
+		}
+		defer watcher.Close()
+		root, gitDir := s.repository.Path(), s.gitDir()
+		s.addWatches(watcher, gitDir)
+		last, _ := s.fingerprint()
+		var settle <-chan time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ev, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if !relevant(root, gitDir, ev.Name) {
+					continue
+				}
+				if ev.Op&fsnotify.Create != 0 {
+					if info, err := os.Stat(ev.Name); err == nil && info.IsDir() {
+						_ = watcher.Add(ev.Name)
+					}
+				}
+				settle = time.After(interval)
+			case _, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+			case <-settle:
+				settle = nil
+				// the reloads may touch the index as well, they are told apart
+				// by the unchanged status
+				current, err := s.fingerprint()
+				if err != nil || current == last {
+					continue
+				}
+				last = current
+				s.prompt.Post(s.refresh)
+			}
+		}
+	}
+}
+
+// refresh reloads the status, or marks it stale if a sub-list is displayed so
+// that it is reloaded when the files are listed again
+func (s *status) refresh() {
+	if s.oldState != nil {
+		s.stale = true
+		return
+	}
+	_ = s.reloadStatus()
+}
+
+// gitDir returns the git directory of the repository, it is not the .git of
+// the working tree in a linked worktree or a submodule where .git is a file
+func (s *status) gitDir() string {
+	root := s.repository.Path()
+	out, err := gitCommand(s.repository, "rev-parse", "--git-dir").Output()
+	if err != nil {
+		return filepath.Join(root, ".git")
+	}
+	dir := strings.TrimSpace(string(out))
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(root, dir)
+	}
+	return filepath.Clean(dir)
+}
+
+// addWatches watches the directories of the tracked and the untracked files,
+// the ignored ones are left out, and the git directory for the index and HEAD
+func (s *status) addWatches(w *fsnotify.Watcher, gitDir string) {
+	root := s.repository.Path()
+	_ = w.Add(root)
+	_ = w.Add(gitDir)
+	cmd := gitCommand(s.repository, "ls-files", "-z", "--cached", "--others", "--exclude-standard", "--directory")
+	out, err := cmd.Output()
+	if err != nil {
+		return
+	}
+	watched := make(map[string]bool)
+	for _, record := range bytes.Split(out, []byte{0}) {
+		path := string(record)
+		dir := filepath.Dir(path)
+		if strings.HasSuffix(path, "/") {
+			dir = strings.TrimSuffix(path, "/") // an untracked directory
+		}
+		for ; dir != "." && dir != "/" && !watched[dir]; dir = filepath.Dir(dir) {
+			watched[dir] = true
+			_ = w.Add(filepath.Join(root, dir))
+		}
+	}
+}
+
+// relevant returns true if the change of the path may change the status, only
+// the index and HEAD are of the git directory
+func relevant(root, gitDir, path string) bool {
+	if rel, err := filepath.Rel(gitDir, path); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return rel == "index" || rel == "HEAD"
+	}
+	// the .git file of a linked worktree only points to the git directory
+	return path != filepath.Join(root, ".git")
+}
+
+// fingerprint summarizes the working tree, it changes if a file is changed,
+// staged or created. It doesn't take the index lock so that it never stands in
+// the way of the commands run by the prompt.
+func (s *status) fingerprint() (string, error) {
+	cmd := gitCommand(s.repository, "status", "--porcelain", "-z")
+	cmd.Env = append(os.Environ(), "GIT_OPTIONAL_LOCKS=0")
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	buf.Write(out)
+	// the listed files may be changed further without changing the output
+	for _, path := range statusPaths(out) {
+		if info, err := os.Stat(filepath.Join(s.repository.Path(), path)); err == nil {
+			fmt.Fprintf(&buf, "%d %d\n", info.Size(), info.ModTime().UnixNano())
+		}
+	}
+	return buf.String(), nil
+}
+
+// statusPaths returns the paths of the git status --porcelain -z output. The
+// origin of a rename or a copy follows its record without the XY prefix, it is
+// skipped since the file is gone or unchanged.
+func statusPaths(out []byte) []string {
+	var paths []string
+	records := bytes.Split(out, []byte{0})
+	for i := 0; i < len(records); i++ {
+		record := records[i]
+		if len(record) < 4 {
+			continue
+		}
+		paths = append(paths, string(record[3:]))
+		if record[0] == 'R' || record[0] == 'C' || record[1] == 'R' || record[1] == 'C' {
+			i++
+		}
+	}
+	return paths
+}
@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStatusPaths(t *testing.T) {
+	var tests = []struct {
+		out  string
+		want string
+	}{
+		{"", ""},
+		{" M a.go\x00?? b.go\x00", "a.go b.go"},
+		{"R  new.go\x00old.go\x00 M c.go\x00", "new.go c.go"},
+		{"C  copy.go\x00orig.go\x00", "copy.go"},
+		{" R renamed.go\x00abc.go\x00", "renamed.go"},
+	}
+	for _, test := range tests {
+		if got := strings.Join(statusPaths([]byte(test.out)), " "); got != test.want {
+			t.Errorf("out: %q\n got %q, want %q", test.out, got, test.want)
+		}
+	}
+}
+
+func TestRelevant(t *testing.T) {
+	var tests = []struct {
+		gitDir, path string
+		want         bool
+	}{
+		{"/repo/.git", "/repo/a.go", true},
+		{"/repo/.git", "/repo/pkg/b.go", true},
+		{"/repo/.git", "/repo/.gitignore", true},
+		{"/repo/.git", "/repo/.git/index", true},
+		{"/repo/.git", "/repo/.git/HEAD", true},
+		{"/repo/.git", "/repo/.git/index.lock", false},
+		{"/repo/.git", "/repo/.git/objects/ab", false},
+		{"/main/.git/worktrees/repo", "/main/.git/worktrees/repo/index", true},
+		{"/main/.git/worktrees/repo", "/main/.git/worktrees/repo/logs/HEAD", false},
+		{"/main/.git/worktrees/repo", "/repo/.git", false},
+		{"/main/.git/worktrees/repo", "/repo/a.go", true},
+	}
+	for _, test := range tests {
+		if got := relevant("/repo", test.gitDir, test.path); got != test.want {
+			t.Errorf("git dir: %s path: %s\n got %v, want %v", test.gitDir, test.path, got, test.want)
+		}
+	}
+}
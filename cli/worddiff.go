@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"unicode"
+)
+
+// maxWordDiff limits the size of the LCS table, longer lines are highlighted
+// as a whole
+const maxWordDiff = 1 << 16
+
+// wordDiff compares the words of the lines and returns the changed runes of
+// each of them, both are nil if the lines are too long to compare
+func wordDiff(old, new string) ([]bool, []bool) {
+	a, b := splitWords(old), splitWords(new)
+	if len(a)*len(b) > maxWordDiff {
+		return nil, nil
+	}
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	removed, added := make([]bool, 0, len(old)), make([]bool, 0, len(new))
+	mark := func(changed []bool, word string, v bool) []bool {
+		for range word {
+			changed = append(changed, v)
+		}
+		return changed
+	}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			removed = mark(removed, a[i], false)
+			added = mark(added, b[j], false)
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			removed = mark(removed, a[i], true)
+			i++
+		default:
+			added = mark(added, b[j], true)
+			j++
+		}
+	}
+	return removed, added
+}
+
+// splitWords splits the text into words, runs of spaces and punctuations so
+// that joining them results in the text
+func splitWords(text string) []string {
+	var words []string
+	start, class := 0, -1
+	for i, r := range text {
+		c := runeClass(r)
+		if c != class || c == 2 {
+			if i > start {
+				words = append(words, text[start:i])
+			}
+			start, class = i, c
+		}
+	}
+	if len(text) > start {
+		words = append(words, text[start:])
+	}
+	return words
+}
+
+// runeClass groups the letters and the digits as words, the spaces as a run
+// and leaves the punctuations alone
+func runeClass(r rune) int {
+	switch {
+	case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+		return 0
+	case unicode.IsSpace(r):
+		return 1
+	default:
+		return 2
+	}
+}
@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"testing"
+)
+
+func TestWordDiff(t *testing.T) {
+	var tests = []struct {
+		old, new       string
+		removed, added string
+	}{
+		{"foo bar", "foo bar", "", ""},
+		{"foo bar", "foo baz", "bar", "baz"},
+		{"a := b(c)", "a := b(c, d)", "", ", d"},
+		{"", "new", "", "new"},
+		{"héllo wörld", "héllo world", "wörld", "world"},
+	}
+	changed := func(text string, mask []bool) string {
+		var s string
+		for i, r := range []rune(text) {
+			if mask[i] {
+				s += string(r)
+			}
+		}
+		return s
+	}
+	for _, test := range tests {
+		removed, added := wordDiff(test.old, test.new)
+		if got := changed(test.old, removed); got != test.removed {
+			t.Errorf("wordDiff(%q, %q) removed %q, want %q", test.old, test.new, got, test.removed)
+		}
+		if got := changed(test.new, added); got != test.added {
+			t.Errorf("wordDiff(%q, %q) added %q, want %q", test.old, test.new, got, test.added)
+		}
+	}
+}
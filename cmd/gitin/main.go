@@ -2,17 +2,26 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 
 	"github.com/isacikgoz/gitin/cli"
 	"github.com/isacikgoz/gitin/git"
 	"github.com/isacikgoz/gitin/prompt"
+	"github.com/isacikgoz/gitin/term"
 
 	env "github.com/kelseyhightower/envconfig"
 	pin "gopkg.in/alecthomas/kingpin.v2"
 )
 
+var (
+	reflogRef *string
+	blamePath *string
+	plain     *bool
+	keys      *string
+)
+
 func main() {
 	mode := evalArgs()
 	pwd, _ := os.Getwd()
@@ -20,11 +29,12 @@ func main() {
 	r, err := git.Open(pwd)
 	exitIfError(err)
 
-	var o prompt.Options
+	var o cli.Options
 	err = env.Process("gitin", &o)
 	exitIfError(err)
 
 	var p *prompt.Prompt
+	cli.GitPath = o.GitPath
 
 	// cli package is for responsible to create and configure a prompt
 	switch mode {
@@ -34,13 +44,42 @@ func main() {
 		p, err = cli.LogPrompt(r, &o)
 	case "branch":
 		p, err = cli.BranchPrompt(r, &o)
+	case "stash":
+		p, err = cli.StashPrompt(r, &o)
+	case "tag":
+		p, err = cli.TagPrompt(r, &o)
+	case "blame":
+		p, err = cli.BlamePrompt(r, *blamePath, &o)
+	case "reflog":
+		p, err = cli.ReflogPrompt(r, &o, *reflogRef)
 	default:
 		return
 	}
 
+	var empty *cli.EmptyError
+	if errors.As(err, &empty) {
+		empty.Print() // nothing to list is not a failure
+		return
+	}
 	exitIfError(err)
 	ctx := context.Background()
-	exitIfError(p.Run(ctx))
+	// the list is printed for the scripts, there is nobody to interact with
+	if !term.IsTerminal(os.Stdout.Fd()) {
+		term.DisableColor()
+		*plain = true
+	} else if !term.IsTerminal(os.Stdin.Fd()) {
+		*plain = true
+	}
+	if *plain {
+		exitIfError(p.Print(ctx, os.Stdout))
+		return
+	}
+	p.Feed([]rune(*keys)...)
+	if err := p.Run(ctx); err == prompt.ErrAborted {
+		os.Exit(130) // like a shell does for SIGINT
+	} else {
+		exitIfError(err)
+	}
 }
 
 func exitIfError(err error) {
@@ -55,6 +94,14 @@ func evalArgs() string {
 	pin.Command("log", "Show commit logs.")
 	pin.Command("status", "Show working-tree status. Also stage and commit changes.")
 	pin.Command("branch", "Show list of branches.")
+	pin.Command("stash", "Show list of stashes. Also apply, pop and drop them.")
+	pin.Command("tag", "Show list of tags. Also create, delete and push them.")
+	blamePath = pin.Command("blame", "Show what revision and author last modified each line of a file.").Arg("file", "File to blame.").Required().String()
+	reflogRef = pin.Command("reflog", "Show reflog of a ref. Also reset HEAD to an entry.").Arg("ref", "Ref to show the reflog of.").Default("HEAD").String()
+
+	plain = pin.Flag("plain", "Print the list and exit without the prompt, it is the default if the output is not a terminal.").Bool()
+
+	keys = pin.Flag("keys", "Keys to press once the prompt starts, e.g. to replay a macro like \"ac\" to stage all and commit.").String()
 
 	pin.Version("gitin version 0.3.0")
 
@@ -71,6 +118,26 @@ func additionalHelp() string {
   GITIN_LINESIZE=<int>
   GITIN_STARTINSEARCH=<bool>
   GITIN_DISABLECOLOR=<bool>
-
-Press ? for controls while application is running.`
+  GITIN_DISABLECLIPBOARD=<bool>
+  GITIN_VIMKEYS=<bool>
+  GITIN_WRAPSCROLL=<bool>
+  GITIN_HISTORYFILE=<path>
+  GITIN_HISTORYSIZE=<int>
+  GITIN_SEARCHDELAY=<duration>
+  GITIN_SEARCHMODE=<fuzzy|substring|regex>
+  GITIN_MINSCORE=<int>
+  GITIN_KEEPORDER=<bool>
+  GITIN_ALTSCREEN=<bool>
+  GITIN_BLINKCURSOR=<bool>
+  GITIN_DRYRUN=<bool>
+  GITIN_QUITKEY=<key>
+  GITIN_HELPKEY=<key>
+  GITIN_HINTS=<bool>
+  GITIN_SORTHELP=<bool>
+  GITIN_REVERSE=<bool>
+  GITIN_WATCH=<duration>
+  GITIN_ICONS=<bool>
+  GITIN_GITPATH=<path>
+
+Press ? (or GITIN_HELPKEY) for controls while application is running.`
 }
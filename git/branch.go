@@ -134,6 +134,11 @@ func (b *Branch) String() string {
 	return b.Name
 }
 
+// Identity is the full name of the branch
+func (b *Branch) Identity() string {
+	return b.FullName
+}
+
 // IsRemote returns false if it is a local branch
 func (b *Branch) IsRemote() bool {
 	return b.isRemote
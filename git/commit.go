@@ -156,6 +156,11 @@ func (c *Commit) String() string {
 	return c.Summary
 }
 
+// Identity is the hash of the commit
+func (c *Commit) Identity() string {
+	return c.Hash
+}
+
 // Amend updates the commit and returns NEW commit pointer
 func (c *Commit) Amend(message string, author ...*Signature) (*Commit, error) {
 	repo := c.owner.essence
@@ -0,0 +1,12 @@
+package git
+
+// ConfigString returns the value of a config variable, e.g. "commit.template".
+// The repository config overrides the global and the system ones.
+func (r *Repository) ConfigString(name string) (string, error) {
+	config, err := r.essence.Config()
+	if err != nil {
+		return "", err
+	}
+	defer config.Free()
+	return config.LookupString(name)
+}
@@ -63,6 +63,14 @@ func initRepoFromPath(path string) (*lib.Repository, string, error) {
 	return nil, walk, errors.New("cannot load a git repository from " + path)
 }
 
+// LoadRefs maps the commits to their branches and tags, the map is built anew
+// so that a moved or deleted ref isn't left on its old commit
+func (r *Repository) LoadRefs() {
+	r.RefMap = make(map[string][]Ref)
+	r.Branches()
+	r.Tags()
+}
+
 // LoadHead can be used to refresh HEAD ref
 func (r *Repository) LoadHead() error {
 	head, err := r.essence.Head()
@@ -21,3 +21,25 @@ func TestOpen(t *testing.T) {
 		}
 	}
 }
+
+func TestLoadRefs(t *testing.T) {
+	wd, _ := os.Getwd()
+	r, err := Open(wd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.LoadRefs()
+	want := len(r.RefMap[r.Head.Hash])
+	r.LoadRefs() // e.g. after a cherry-pick
+	refs := r.RefMap[r.Head.Hash]
+	if len(refs) != want {
+		t.Errorf("got %d refs of HEAD after reloading, want %d", len(refs), want)
+	}
+	seen := make(map[string]bool)
+	for _, ref := range refs {
+		if seen[ref.String()] {
+			t.Errorf("ref %s is listed twice", ref.String())
+		}
+		seen[ref.String()] = true
+	}
+}
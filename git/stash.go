@@ -0,0 +1,66 @@
+package git
+
+import (
+	"strconv"
+	"strings"
+
+	lib "github.com/libgit2/git2go/v33"
+)
+
+// Stash is a saved state of the working directory
+type Stash struct {
+	target *Commit
+
+	Index   int
+	Hash    string
+	Message string
+	Branch  string
+}
+
+// Stashes loads the stash entries, the latest one comes first
+func (r *Repository) Stashes() ([]*Stash, error) {
+	buffer := make([]*Stash, 0)
+	err := r.essence.Stashes.Foreach(func(index int, message string, id *lib.Oid) error {
+		s := &Stash{
+			Index:   index,
+			Hash:    id.String(),
+			Message: message,
+		}
+		// messages are in "WIP on <branch>: <summary>" or "On <branch>: <msg>" form
+		if i := strings.Index(message, ": "); i > 0 {
+			for _, prefix := range []string{"WIP on ", "On "} {
+				if strings.HasPrefix(message, prefix) {
+					s.Branch = message[len(prefix):i]
+					s.Message = message[i+2:]
+					break
+				}
+			}
+		}
+		if commit, err := r.essence.LookupCommit(id); err == nil {
+			s.target = unpackRawCommit(r, commit)
+		}
+		buffer = append(buffer, s)
+		return nil
+	})
+	return buffer, err
+}
+
+// Target is the commit that the stash is recorded as
+func (s *Stash) Target() *Commit {
+	return s.target
+}
+
+// Ref is the name of the stash to be used with git commands
+func (s *Stash) Ref() string {
+	return "stash@{" + strconv.Itoa(s.Index) + "}"
+}
+
+func (s *Stash) String() string {
+	return s.Message
+}
+
+// Identity is the hash of the stash since the index changes as the stashes are
+// pushed and dropped
+func (s *Stash) Identity() string {
+	return s.Hash
+}
@@ -49,6 +49,7 @@ const (
 	IndexTypeUnstaged
 	IndexTypeUntracked
 	IndexTypeConflicted
+	IndexTypeIgnored
 )
 
 // StatusEntryType describes the type of change a status entry has undergone
@@ -63,6 +64,7 @@ const (
 	StatusEntryTypeUntracked
 	StatusEntryTypeTypeChange
 	StatusEntryTypeConflicted
+	StatusEntryTypeIgnored
 )
 
 var indexTypeMap = map[lib.Status]IndexType{
@@ -70,6 +72,7 @@ var indexTypeMap = map[lib.Status]IndexType{
 	lib.StatusWtModified | lib.StatusWtDeleted | lib.StatusWtTypeChange | lib.StatusWtRenamed:                                  IndexTypeUnstaged,
 	lib.StatusWtNew:      IndexTypeUntracked,
 	lib.StatusConflicted: IndexTypeConflicted,
+	lib.StatusIgnored:    IndexTypeIgnored,
 }
 
 var statusEntryTypeMap = map[lib.Status]StatusEntryType{
@@ -84,6 +87,7 @@ var statusEntryTypeMap = map[lib.Status]StatusEntryType{
 	lib.StatusWtTypeChange:    StatusEntryTypeTypeChange,
 	lib.StatusWtNew:           StatusEntryTypeUntracked,
 	lib.StatusConflicted:      StatusEntryTypeConflicted,
+	lib.StatusIgnored:         StatusEntryTypeIgnored,
 }
 
 // StatusEntry contains data for a single status entry
@@ -111,6 +115,12 @@ func (d *Diff) Deltas() []*DiffDelta {
 	return d.deltas
 }
 
+// Stats returns the changed files with their insertions and deletions, the
+// last line is the summary of the changes
+func (d *Diff) Stats() []string {
+	return d.stats
+}
+
 // DiffDelta holds delta status, file changes and the actual patchs
 type DiffDelta struct {
 	Status  DeltaStatus
@@ -130,12 +140,35 @@ func (d *DiffDelta) String() string {
 	return d.OldFile.Path
 }
 
-// LoadStatus simply emulates a "git status" and returns the result
-func (r *Repository) LoadStatus() (*Status, error) {
+// StatusOptions selects the files listed besides the changes of the tracked
+// ones
+type StatusOptions struct {
+	Untracked bool
+	Ignored   bool
+}
+
+// DefaultStatusOptions lists the untracked files but not the ignored ones,
+// like git status
+var DefaultStatusOptions = StatusOptions{Untracked: true}
+
+// LoadStatus simply emulates a "git status" and returns the result, nil opts
+// are the DefaultStatusOptions
+func (r *Repository) LoadStatus(opts *StatusOptions) (*Status, error) {
+	if opts == nil {
+		def := DefaultStatusOptions
+		opts = &def
+	}
+	var flags lib.StatusOpt
+	if opts.Untracked {
+		flags |= lib.StatusOptIncludeUntracked
+	}
+	if opts.Ignored {
+		flags |= lib.StatusOptIncludeIgnored
+	}
 	// this returns err does it matter?
 	statusOptions := &lib.StatusOptions{
 		Show:  lib.StatusShowIndexAndWorkdir,
-		Flags: lib.StatusOptIncludeUntracked,
+		Flags: flags,
 	}
 	statusList, err := r.essence.StatusList(statusOptions)
 	if err != nil {
@@ -200,11 +233,27 @@ func (e *StatusEntry) String() string {
 	return e.diffDelta.OldFile.Path
 }
 
+// Identity is the path of the entry so that it is matched after it is staged
+func (e *StatusEntry) Identity() string {
+	return e.diffDelta.OldFile.Path
+}
+
 // Indexed true if entry added to index
 func (e *StatusEntry) Indexed() bool {
 	return e.index == IndexTypeStaged
 }
 
+// Untracked true if the file is not in the index, an untracked or an ignored
+// one, so that it has no diff
+func (e *StatusEntry) Untracked() bool {
+	return e.EntryType == StatusEntryTypeUntracked || e.EntryType == StatusEntryTypeIgnored
+}
+
+// Conflicted true if entry has unresolved conflicts of a merge, a rebase etc.
+func (e *StatusEntry) Conflicted() bool {
+	return e.EntryType == StatusEntryTypeConflicted
+}
+
 // StatusEntryString returns entry status in pretty format
 func (e *StatusEntry) StatusEntryString() string {
 	switch e.EntryType {
@@ -222,6 +271,8 @@ func (e *StatusEntry) StatusEntryString() string {
 		return "Type change"
 	case StatusEntryTypeConflicted:
 		return "Conflicted"
+	case StatusEntryTypeIgnored:
+		return "Ignored"
 	default:
 		return "Unknown"
 	}
@@ -1,5 +1,9 @@
 package git
 
+import (
+	lib "github.com/libgit2/git2go/v33"
+)
+
 // Tag is used to label and mark a specific commit in the history.
 type Tag struct {
 	target  *Commit
@@ -8,6 +12,11 @@ type Tag struct {
 	Hash      string
 	Shorthand string
 	Name      string
+
+	// set if the tag is annotated
+	Annotated bool
+	Message   string
+	Tagger    *Signature
 }
 
 // Tags loads tags from the refs
@@ -33,6 +42,26 @@ func (r *Repository) Tags() ([]*Tag, error) {
 				Name:      ref.Name(),
 				Shorthand: ref.Shorthand(),
 			}
+			obj, err := r.essence.RevparseSingle(ref.Target().String())
+			if err == nil && obj != nil {
+				if obj.Type() == lib.ObjectTag {
+					// annotated tags point to a tag object instead of the commit
+					if tag, err := obj.AsTag(); err == nil {
+						t.Annotated = true
+						t.Message = tag.Message()
+						if sig := tag.Tagger(); sig != nil {
+							t.Tagger = &Signature{Name: sig.Name, Email: sig.Email, When: sig.When}
+						}
+					}
+					obj, err = obj.Peel(lib.ObjectCommit)
+				}
+				if err == nil && obj != nil {
+					if commit, _ := obj.AsCommit(); commit != nil {
+						t.target = unpackRawCommit(r, commit)
+						t.Hash = t.target.Hash
+					}
+				}
+			}
 			// add to refmap
 			if _, ok := r.RefMap[t.Hash]; !ok {
 				r.RefMap[t.Hash] = make([]Ref, 0)
@@ -40,13 +69,6 @@ func (r *Repository) Tags() ([]*Tag, error) {
 			refs := r.RefMap[t.Hash]
 			refs = append(refs, t)
 			r.RefMap[t.Hash] = refs
-
-			obj, err := r.essence.RevparseSingle(ref.Target().String())
-			if err == nil && obj != nil {
-				if commit, _ := obj.AsCommit(); commit != nil {
-					t.target = unpackRawCommit(r, commit)
-				}
-			}
 			buffer = append(buffer, t)
 		}
 	}
@@ -66,3 +88,8 @@ func (t *Tag) Target() *Commit {
 func (t *Tag) String() string {
 	return t.Shorthand
 }
+
+// Identity is the full name of the tag
+func (t *Tag) Identity() string {
+	return t.Name
+}
@@ -6,12 +6,15 @@ replace github.com/libgit2/git2go/v33 => ../git2go
 
 require (
 	github.com/fatih/color v1.9.0
+	github.com/fsnotify/fsnotify v1.4.9
 	github.com/isacikgoz/fuzzy v0.2.0
 	github.com/isacikgoz/gia v0.2.0
 	github.com/justincampbell/timeago v0.0.0-20160528003754-027f40306f1d
 	github.com/kelseyhightower/envconfig v1.4.0
 	github.com/libgit2/git2go/v33 v33.0.9
+	github.com/mattn/go-runewidth v0.0.4
 	github.com/waigani/diffparser v0.0.0-20190828052634-7391f219313d
+	golang.org/x/sys v0.0.0-20201204225414-ed752295db88
 	gopkg.in/alecthomas/kingpin.v2 v2.2.6
 )
 
@@ -22,8 +25,6 @@ require (
 	github.com/justincampbell/bigduration v0.0.0-20160531141349-e45bf03c0666 // indirect
 	github.com/mattn/go-colorable v0.1.4 // indirect
 	github.com/mattn/go-isatty v0.0.11 // indirect
-	github.com/mattn/go-runewidth v0.0.4 // indirect
 	github.com/nsf/termbox-go v0.0.0-20190325093121-288510b9734e // indirect
 	golang.org/x/crypto v0.0.0-20201203163018-be400aefbc4c // indirect
-	golang.org/x/sys v0.0.0-20201204225414-ed752295db88 // indirect
 )
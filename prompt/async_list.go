@@ -7,34 +7,58 @@ import (
 	"sort"
 	"strings"
 	"sync"
-	"sync/atomic"
+	"time"
 
 	"github.com/isacikgoz/fuzzy"
 )
 
+// The received items are added to the list in batches of asyncFlushSize, a
+// smaller batch is added after asyncFlushInterval or once the first screenful
+// is received so that the first items are displayed even if the rest takes
+// time.
+const (
+	asyncFlushSize     = 4096
+	asyncFlushInterval = 100 * time.Millisecond
+)
+
 // AsyncList holds a collection of items that can be displayed with an N number of
 // visible items. The list can be moved up, down by one item of time or an
 // entire page (ie: visible size). It keeps track of the current selected item.
 type AsyncList struct {
-	itemsChan chan interface{}
-	items     []interface{}
-	scope     []interface{}
-	buffer    []interface{}
-	matches   sync.Map
-	cursor    int // cursor holds the index of the current selected item
-	size      int // size is the number of visible options
-	start     int
-	find      string
-	mx        sync.Mutex
-	update    chan struct{}
-	ctx       *searchContext
+	itemsChan  chan interface{}
+	items      []interface{}
+	scope      []interface{}
+	buffer     []interface{}
+	matches    map[interface{}][]int
+	cursor     int // cursor holds the index of the current selected item
+	size       int // size is the number of visible options
+	start      int
+	find       string
+	wrap       bool // wrap around when moving past either end
+	loading    bool // items are still being read from the channel
+	mode       SearchMode
+	key        func(interface{}) string    // nil matches the items by fmt.Sprint
+	minScore   int                         // the weaker fuzzy matches are dropped
+	keepOrder  bool                        // the matches are not sorted by their scores
+	equal      func(a, b interface{}) bool // nil compares the items with sameItem
+	less       func(a, b interface{}) bool // nil keeps the items in their order
+	reverse    bool                        // the items received later are listed at the top
+	unsorted   []interface{}               // the items in their original order
+	filter     func(interface{}) bool      // nil lists all of the items
+	pool       []interface{}               // the items passing the filter, searched and listed
+	saved      interface{}                 // the selected item before the search started
+	savedStart int
+	mx         sync.Mutex // guards the fields above, except the buffer
+	update     chan struct{}
+	ctx        *searchContext
 }
 
+// searchContext belongs to a single search, it is replaced by a new one on
+// each search so that the results of a cancelled search can be discarded
 type searchContext struct {
-	ctx      context.Context
-	cancel   func()
-	buffer   []fuzzy.Match
-	progress int32
+	ctx    context.Context
+	cancel func()
+	buffer []fuzzy.Match
 }
 
 func newSearchContext(c context.Context) *searchContext {
@@ -55,21 +79,11 @@ func (c *searchContext) clearBuffer() {
 }
 
 func (c *searchContext) searchInProgress() bool {
-	return atomic.LoadInt32(&c.progress) != 0
+	return c.ctx.Err() == nil
 }
 
 func (c *searchContext) stopSearch() {
-	if atomic.LoadInt32(&c.progress) == 0 {
-		return
-	}
-
 	c.cancel()
-	c.clearBuffer()
-}
-
-func (c *searchContext) startSearch() bool {
-	c.ctx, c.cancel = context.WithCancel(context.Background())
-	return atomic.CompareAndSwapInt32(&c.progress, 0, 1)
 }
 
 // NewAsyncList creates and initializes a list of searchable items. The items attribute must be a slice type.
@@ -88,46 +102,85 @@ func NewAsyncList(items chan interface{}, size int) (*AsyncList, error) {
 		items:     is,
 		itemsChan: items,
 		scope:     is,
+		pool:      is,
+		matches:   make(map[interface{}][]int),
 		mx:        sync.Mutex{},
-		update:    make(chan struct{}),
+		update:    make(chan struct{}, 1),
 		buffer:    make([]interface{}, 0),
 		ctx:       newSearchContext(context.Background()),
+		loading:   true,
 	}
 
-	go func() {
-		var flush int
-		for val := range items {
-			list.addBuffer(val)
-			if flush < 4096 {
-				flush++
-				continue
+	go list.receive(items, size)
+	return list, nil
+}
+
+// receive reads the items from the channel until it is closed, screen is the
+// number of the items filling the first screen
+func (l *AsyncList) receive(items chan interface{}, screen int) {
+	var received int
+	ticker := time.NewTicker(asyncFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case val, ok := <-items:
+			if !ok {
+				l.flushBuffer()
+				l.mx.Lock()
+				l.loading = false
+				l.mx.Unlock()
+				l.notify()
+				return
 			}
-			list.flushBuffer()
-			flush = 0
+			l.addBuffer(val)
+			if received++; received == screen || len(l.buffer) >= asyncFlushSize {
+				l.flushBuffer()
+			}
+		case <-ticker.C:
+			l.flushBuffer()
 		}
-		list.flushBuffer()
-	}()
-
-	return list, nil
+	}
 }
 
+// flushBuffer moves the buffered items to the list, it is only called by the
+// goroutine reading the items channel
 func (l *AsyncList) flushBuffer() {
-	l.mx.Lock()
-	defer l.mx.Unlock()
-
 	if len(l.buffer) == 0 {
 		return
 	}
 
-	l.items = append(l.items, l.buffer...)
-	l.scope = append(l.scope, l.buffer...)
+	l.mx.Lock()
+	if l.less != nil || l.reverse {
+		l.unsorted = append(l.unsorted, l.buffer...)
+		l.items = sortItems(l.unsorted, l.less, l.reverse)
+	} else {
+		l.items = append(l.items, l.buffer...)
+	}
+	l.pool = filterItems(l.items, l.filter)
+	find, ctx, mode, key, minScore, filter := l.find, l.ctx, l.mode, l.key, l.minScore, l.filter
+	if len(find) == 0 {
+		selected := l.selected()
+		l.scope = l.pool
+		if l.less != nil || l.reverse {
+			l.keepSelection(selected)
+		}
+		l.fixCursor()
+	}
+	l.mx.Unlock()
+
+	// the active search has only seen the items present when it started, so
+	// the new items are matched against the term separately
+	if len(find) > 0 {
+		batch := filterItems(l.buffer, filter)
+		matches := make([]fuzzy.Match, 0)
+		for match := range findFrom(ctx.ctx, mode, find, interfaceSource{batch, key}, minScore) {
+			matches = append(matches, match)
+		}
+		l.flushToScope(ctx, batch, matches, false)
+	}
 
-	// // update for the first iteration of flushing the buffer
-	// // then, a user input will trigger a re-rendering operation
-	// if l.update != nil {
-	l.update <- struct{}{}
-	// }
-	// l.update = nil
+	// notify after unlocking so that the renderer can read the items
+	l.notify()
 
 	l.buffer = make([]interface{}, 0)
 }
@@ -138,72 +191,236 @@ func (l *AsyncList) addBuffer(items ...interface{}) {
 
 // Prev moves the visible list back one item.
 func (l *AsyncList) Prev() {
-	if l.cursor > 0 {
-		l.cursor--
-	}
+	l.mx.Lock()
+	defer l.mx.Unlock()
 
-	if l.start > l.cursor {
-		l.start = l.cursor
+	i := nextItem(l.scope, l.cursor-1, -1)
+	if i == NotFound && l.wrap {
+		i = nextItem(l.scope, len(l.scope)-1, -1)
 	}
+	if i != NotFound {
+		l.setCursor(i)
+	}
+	l.fixCursor()
 }
 
-// Search allows the list to be filtered by a given term.
+// Search allows the list to be filtered by a given term. The selection before
+// the search is restored once the term is cleared.
 func (l *AsyncList) Search(term string) {
 	l.mx.Lock()
 	defer l.mx.Unlock()
 
 	term = strings.Trim(term, " ")
+	cleared := len(term) == 0 && len(l.find) > 0
+	if len(term) > 0 && len(l.find) == 0 {
+		l.saved, l.savedStart = l.selected(), l.start
+	}
 	l.cursor = 0
 	l.start = 0
 	l.find = term
 	l.search(term)
+	if cleared {
+		l.restoreSelection()
+		return
+	}
+	l.fixCursor()
 }
 
-// CancelSearch stops the current search and returns the list to its original order.
+// CancelSearch stops the current search and returns the list to its original
+// order, with the item selected before the search.
 func (l *AsyncList) CancelSearch() {
-	l.cursor = 0
-	l.start = 0
-	l.scope = l.items
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	l.ctx.stopSearch()
+	if len(l.find) == 0 {
+		return
+	}
+	l.find = ""
+	l.scope = l.pool
+	l.restoreSelection()
+}
+
+// restoreSelection moves the cursor back to the item selected before the
+// search if it is still listed, otherwise to the top. It must be called while
+// holding the lock.
+func (l *AsyncList) restoreSelection() {
+	l.cursor, l.start = 0, 0
+	for i, item := range l.scope {
+		if l.same(item, l.saved) {
+			l.start = l.savedStart
+			l.setCursor(i)
+			break
+		}
+	}
+	l.saved = nil
+	l.fixCursor()
 }
 
-func (l *AsyncList) flushToScope(ctx *searchContext, fireUpdate bool) {
-	defer ctx.clearBuffer()
+// SetSort orders the items by less, a nil less restores their original order.
+// A search is run again and the cursor is kept on the selected item, the items
+// received later are sorted as well.
+func (l *AsyncList) SetSort(less func(a, b interface{}) bool) {
+	l.mx.Lock()
+	defer l.mx.Unlock()
 
-	sort.Stable(fuzzy.Sortable(ctx.buffer))
-	for _, match := range ctx.buffer {
-		item := l.items[match.Index]
+	l.less = less
+	l.reorder()
+}
+
+// SetReverse lists the items in the reverse order, after they are sorted. The
+// whole list is reversed including the items received later, which are listed
+// at the top while the cursor is kept on the selected item.
+func (l *AsyncList) SetReverse(reverse bool) {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	if l.reverse == reverse {
+		return
+	}
+	l.reverse = reverse
+	l.reorder()
+}
+
+// reorder applies the sort and the reverse options to the items, it must be
+// called while holding the lock
+func (l *AsyncList) reorder() {
+	selected := l.selected()
+	if l.unsorted == nil {
+		l.unsorted = l.items
+	}
+	l.items = sortItems(l.unsorted, l.less, l.reverse)
+	if l.less == nil && !l.reverse {
+		l.unsorted = nil
+	}
+	l.pool = filterItems(l.items, l.filter)
+	l.search(l.find)
+	l.keepSelection(selected)
+}
+
+// Filter narrows the list to the items that filter returns true for, e.g. the
+// merge commits, regardless of their text. It is combined with the search and
+// applies to the items received later, a nil filter lists all of the items
+// again. The cursor is kept on the selected item if it passes.
+func (l *AsyncList) Filter(filter func(interface{}) bool) {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	selected := l.selected()
+	l.filter = filter
+	l.pool = filterItems(l.items, filter)
+	l.search(l.find)
+	l.keepSelection(selected)
+}
+
+// selected returns the item under the cursor, nil if the scope is empty. It
+// must be called while holding the lock.
+func (l *AsyncList) selected() interface{} {
+	if len(l.scope) == 0 {
+		return nil
+	}
+	return l.scope[l.cursor]
+}
+
+// keepSelection moves the cursor to the item if it is in the scope, otherwise
+// to the top. It must be called while holding the lock.
+func (l *AsyncList) keepSelection(item interface{}) {
+	l.cursor, l.start = 0, 0
+	for i, it := range l.scope {
+		if l.same(it, item) {
+			l.setCursor(i)
+			break
+		}
+	}
+	l.fixCursor()
+}
+
+// fixCursor moves the cursor off a header to the following item, or to the
+// preceding one at the end, and scrolls to the header of the selected item if
+// it is just out of the view. It must be called while holding the lock.
+func (l *AsyncList) fixCursor() {
+	if len(l.scope) == 0 {
+		return
+	}
+	if isHeader(l.scope[l.cursor]) {
+		i := nextItem(l.scope, l.cursor, 1)
+		if i == NotFound {
+			i = nextItem(l.scope, l.cursor, -1)
+		}
+		if i != NotFound {
+			l.setCursor(i)
+		}
+	}
+	if l.size > 1 && l.cursor > 0 && l.start == l.cursor && isHeader(l.scope[l.cursor-1]) {
+		l.start--
+	}
+}
+
+// flushToScope adds the matches of the search to the scope unless the search
+// is already replaced by another one
+func (l *AsyncList) flushToScope(ctx *searchContext, items []interface{}, matches []fuzzy.Match, fireUpdate bool) {
+	l.mx.Lock()
+	if l.ctx != ctx {
+		l.mx.Unlock()
+		return
+	}
+	if !l.keepOrder {
+		sort.Stable(fuzzy.Sortable(matches))
+	}
+	for _, match := range matches {
+		item := items[match.Index]
+		if isHeader(item) {
+			continue
+		}
 		l.scope = append(l.scope, item)
-		l.matches.Store(item, match.MatchedIndexes)
+		l.matches[item] = match.MatchedIndexes
 	}
-	if fireUpdate && l.update != nil {
-		l.update <- struct{}{}
+	l.mx.Unlock()
+
+	if fireUpdate {
+		l.notify()
 	}
 }
 
+// notify signals the update without waiting for the renderer, a pending
+// update already covers the changes since the renderer reads the list anew
+func (l *AsyncList) notify() {
+	select {
+	case l.update <- struct{}{}:
+	default:
+	}
+}
+
+// search starts matching the items in the background, it must be called while
+// holding the lock
 func (l *AsyncList) search(term string) {
+	l.ctx.stopSearch()
 	if len(term) == 0 {
-		l.scope = l.items
+		l.scope = l.pool
 		return
 	}
 
-	l.ctx.stopSearch()
-	l.matches = sync.Map{}
+	l.matches = make(map[interface{}][]int)
 	l.scope = make([]interface{}, 0)
 
-	l.ctx.startSearch()
-	results := fuzzy.FindFrom(context.Background(), term, interfaceSource(l.items))
+	ctx := newSearchContext(context.Background())
+	l.ctx = ctx
+	items := l.pool
+	size := l.size
+	results := findFrom(ctx.ctx, l.mode, term, interfaceSource{items, l.key}, l.minScore)
 
 	go func() {
 		var flush int
 		var done bool
 		for result := range results {
-			if !l.ctx.searchInProgress() {
-				break
+			if !ctx.searchInProgress() {
+				continue // drain the results so that the matcher can return
 			}
-			l.ctx.addBuffer(result)
+			ctx.addBuffer(result)
 
-			if !done && flush == l.size {
-				l.flushToScope(l.ctx, true)
+			if !done && flush == size {
+				l.flushToScope(ctx, items, ctx.buffer, true)
+				ctx.clearBuffer()
 				done = true
 				continue
 			}
@@ -213,21 +430,96 @@ func (l *AsyncList) search(term string) {
 				continue
 			}
 
-			l.flushToScope(l.ctx, false)
+			l.flushToScope(ctx, items, ctx.buffer, false)
+			ctx.clearBuffer()
 			flush = 0
 		}
-		l.flushToScope(l.ctx, true)
-		l.ctx.clearBuffer()
+		l.flushToScope(ctx, items, ctx.buffer, true)
+		ctx.clearBuffer()
 	}()
 }
 
+// SetSearchMode changes the matching strategy and filters the list again with
+// the current term.
+func (l *AsyncList) SetSearchMode(mode SearchMode) {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	if l.mode == mode {
+		return
+	}
+	l.mode = mode
+	if len(l.find) > 0 {
+		l.cursor = 0
+		l.start = 0
+		l.search(l.find)
+	}
+}
+
+// SetSearchKey makes the search match the items by the text that key returns
+// instead of their default format, the matched indexes are the offsets in the
+// key. The list is filtered again with the current term.
+func (l *AsyncList) SetSearchKey(key func(interface{}) string) {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	l.key = key
+	if len(l.find) > 0 {
+		l.cursor = 0
+		l.start = 0
+		l.search(l.find)
+	}
+}
+
+// SetEqual replaces the comparison of the items that keeps the selection, e.g.
+// to compare the value items by one of their fields
+func (l *AsyncList) SetEqual(equal func(a, b interface{}) bool) {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	l.equal = equal
+}
+
+// same returns true if the items are the same item, it must be called while
+// holding the lock
+func (l *AsyncList) same(a, b interface{}) bool {
+	if l.equal != nil {
+		return l.equal(a, b)
+	}
+	return sameItem(a, b)
+}
+
+// SetScoring drops the fuzzy matches scoring below minScore, 0 keeps all of
+// them, and lists the matches in the order of the items instead of by their
+// scores if keepOrder is set. The list is filtered again with the current term.
+func (l *AsyncList) SetScoring(minScore int, keepOrder bool) {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	if l.minScore == minScore && l.keepOrder == keepOrder {
+		return
+	}
+	l.minScore, l.keepOrder = minScore, keepOrder
+	if len(l.find) > 0 {
+		l.cursor = 0
+		l.start = 0
+		l.search(l.find)
+	}
+}
+
 // Start returns the current render start position of the list.
 func (l *AsyncList) Start() int {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
 	return l.start
 }
 
 // SetStart sets the current scroll position. Values out of bounds will be clamped.
 func (l *AsyncList) SetStart(i int) {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
 	if i < 0 {
 		i = 0
 	}
@@ -239,8 +531,16 @@ func (l *AsyncList) SetStart(i int) {
 }
 
 // SetCursor sets the position of the cursor in the list. Values out of bounds will
-// be clamped.
+// be clamped, a header moves the cursor to the following item.
 func (l *AsyncList) SetCursor(i int) {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	l.setCursor(i)
+	l.fixCursor()
+}
+
+func (l *AsyncList) setCursor(i int) {
 	max := len(l.scope) - 1
 	if i >= max {
 		i = max
@@ -259,20 +559,25 @@ func (l *AsyncList) SetCursor(i int) {
 
 // Next moves the visible list forward one item.
 func (l *AsyncList) Next() {
-	max := len(l.scope) - 1
+	l.mx.Lock()
+	defer l.mx.Unlock()
 
-	if l.cursor < max {
-		l.cursor++
+	i := nextItem(l.scope, l.cursor+1, 1)
+	if i == NotFound && l.wrap {
+		i = nextItem(l.scope, 0, 1)
 	}
-
-	if l.start+l.size <= l.cursor {
-		l.start = l.cursor - l.size + 1
+	if i != NotFound {
+		l.setCursor(i)
 	}
+	l.fixCursor()
 }
 
 // PageUp moves the visible list backward by x items. Where x is the size of the
 // visible items on the list.
 func (l *AsyncList) PageUp() {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
 	start := l.start - l.size
 	if start < 0 {
 		l.start = 0
@@ -285,11 +590,15 @@ func (l *AsyncList) PageUp() {
 	if cursor < l.cursor {
 		l.cursor = cursor
 	}
+	l.fixCursor()
 }
 
 // PageDown moves the visible list forward by x items. Where x is the size of
 // the visible items on the list.
 func (l *AsyncList) PageDown() {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
 	start := l.start + l.size
 	max := len(l.scope) - l.size
 
@@ -309,28 +618,38 @@ func (l *AsyncList) PageDown() {
 	} else if cursor > l.cursor {
 		l.cursor = cursor
 	}
+	l.fixCursor()
 }
 
 // CanPageDown returns whether a list can still PageDown().
 func (l *AsyncList) CanPageDown() bool {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
 	max := len(l.scope)
 	return l.start+l.size < max
 }
 
 // CanPageUp returns whether a list can still PageUp().
 func (l *AsyncList) CanPageUp() bool {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
 	return l.start > 0
 }
 
 // Index returns the index of the item currently selected inside the searched list.
 func (l *AsyncList) Index() int {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
 	if len(l.scope) <= 0 {
 		return 0
 	}
 	selected := l.scope[l.cursor]
 
 	for i, item := range l.items {
-		if item == selected {
+		if l.same(item, selected) {
 			return i
 		}
 	}
@@ -341,6 +660,9 @@ func (l *AsyncList) Index() int {
 // Items returns a slice equal to the size of the list with the current visible
 // items and the index of the active item in this list.
 func (l *AsyncList) Items() ([]interface{}, int) {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
 	var result []interface{}
 	max := len(l.scope)
 	end := l.start + l.size
@@ -363,19 +685,102 @@ func (l *AsyncList) Items() ([]interface{}, int) {
 }
 
 func (l *AsyncList) Size() int {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
 	return l.size
 }
 
+// SetSize changes the number of the visible items, the list is scrolled to
+// keep the cursor visible and to fill the rows if it has grown.
+func (l *AsyncList) SetSize(size int) {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	if size < 1 {
+		return
+	}
+	l.size = size
+	if l.start+l.size <= l.cursor {
+		l.start = l.cursor - l.size + 1
+	}
+	if l.start+l.size > len(l.scope) {
+		if l.start = len(l.scope) - l.size; l.start < 0 {
+			l.start = 0
+		}
+	}
+}
+
+// SetWrapScroll makes Next and Prev wrap around at the ends of the list.
+func (l *AsyncList) SetWrapScroll(wrap bool) {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	l.wrap = wrap
+}
+
+func (l *AsyncList) Len() int {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	return len(l.scope)
+}
+
+// Total is the number of the received items regardless of the search, the
+// headers are not counted
+func (l *AsyncList) Total() int {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	return countItems(l.items)
+}
+
+// Position returns the 1-based position of the selected item and the number of
+// the items in the scope, the headers are not counted
+func (l *AsyncList) Position() (int, int) {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	if len(l.scope) == 0 {
+		return 0, 0
+	}
+	return countItems(l.scope[:l.cursor+1]), countItems(l.scope)
+}
+
 func (l *AsyncList) Cursor() int {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
 	return l.cursor
 }
 
 func (l *AsyncList) Matches(key interface{}) []int {
-	v, ok := l.matches.Load(key)
-	if !ok {
-		return make([]int, 0)
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	return l.matches[key]
+}
+
+// Find returns the position of the first item in the scope that match returns
+// true for, or NotFound if there is none
+func (l *AsyncList) Find(match func(item interface{}) bool) int {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	for i, item := range l.scope {
+		if match(item) {
+			return i
+		}
 	}
-	return v.([]int)
+	return NotFound
+}
+
+// Loading returns true until the items channel is closed.
+func (l *AsyncList) Loading() bool {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	return l.loading
 }
 
 func (l *AsyncList) Update() chan struct{} {
@@ -0,0 +1,300 @@
+package prompt
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAsyncListConcurrentAccess(t *testing.T) {
+	items := make(chan interface{})
+	l, err := NewAsyncList(items, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		for range l.Update() {
+		}
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20000; i++ {
+			items <- strconv.Itoa(i)
+		}
+		close(items)
+	}()
+
+	var tests = []string{"1", "12", "", "99", "3"}
+	for i := 0; i < 200; i++ {
+		l.Next()
+		l.PageDown()
+		l.Prev()
+		l.PageUp()
+		l.SetCursor(i)
+		l.Items()
+		l.Index()
+		l.Len()
+		l.Matches("1")
+		if i%40 == 0 {
+			l.Search(tests[i/40])
+		}
+	}
+	l.CancelSearch()
+	wg.Wait()
+}
+
+func TestAsyncListSearchStreamedItems(t *testing.T) {
+	items := make(chan interface{})
+	l, err := NewAsyncList(items, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Search("foo")
+
+	go func() {
+		for _, item := range []string{"foo1", "bar", "foo2", "baz"} {
+			items <- item
+		}
+		close(items)
+	}()
+
+	timeout := time.After(time.Second)
+	for l.Len() < 2 {
+		select {
+		case <-l.Update():
+		case <-timeout:
+			t.Fatalf("streamed items are not matched, scope has %d items", l.Len())
+		}
+	}
+
+	visible, _ := l.Items()
+	for _, item := range visible {
+		if !strings.HasPrefix(item.(string), "foo") {
+			t.Errorf("item %q should not match", item)
+		}
+	}
+}
+
+func TestAsyncListEarlyItems(t *testing.T) {
+	items := make(chan interface{})
+	l, err := NewAsyncList(items, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer close(items)
+	// fewer items than a batch, and the channel is kept open
+	for _, item := range []string{"a", "b", "c"} {
+		items <- item
+	}
+
+	timeout := time.After(time.Second)
+	for l.Len() < 3 {
+		select {
+		case <-l.Update():
+		case <-timeout:
+			t.Fatalf("the first items are not listed, got %d items", l.Len())
+		}
+	}
+	if !l.Loading() {
+		t.Errorf("list should be loading while the channel is open")
+	}
+}
+
+func TestAsyncListFirstScreen(t *testing.T) {
+	var tests = []struct {
+		items []string
+		want  int
+	}{
+		{[]string{"a", "b"}, 2},
+		{[]string{"a", "b", "c"}, 3},
+		{[]string{"a", "b", "c", "d", "e"}, 3},
+	}
+	for _, test := range tests {
+		items := make(chan interface{})
+		l, err := NewAsyncList(items, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		done := make(chan struct{})
+		go func() {
+			for _, item := range test.items {
+				items <- item
+			}
+			<-done
+			close(items)
+		}()
+
+		select {
+		case <-l.Update():
+		case <-time.After(time.Second):
+			t.Fatalf("no update for %v", test.items)
+		}
+		if l.Len() != test.want {
+			t.Errorf("first update of %v has %d items, want %d", test.items, l.Len(), test.want)
+		}
+		close(done)
+		for l.Loading() {
+			<-l.Update()
+		}
+		if l.Len() != len(test.items) {
+			t.Errorf("list of %v has %d items after loading", test.items, l.Len())
+		}
+	}
+}
+
+func TestAsyncListSlowRenderer(t *testing.T) {
+	items := make(chan interface{})
+	l, err := NewAsyncList(items, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sent := make(chan struct{})
+	go func() {
+		for i := 0; i < 10000; i++ {
+			items <- strconv.Itoa(i)
+		}
+		close(items)
+		close(sent)
+	}()
+
+	// the updates are not read until all of the items are received
+	select {
+	case <-sent:
+	case <-time.After(5 * time.Second):
+		t.Fatal("receiving the items is blocked by the unread updates")
+	}
+	timeout := time.After(time.Second)
+	for l.Loading() {
+		select {
+		case <-l.Update():
+			time.Sleep(10 * time.Millisecond)
+		case <-timeout:
+			t.Fatal("no update after the channel is closed")
+		}
+	}
+	if l.Len() != 10000 {
+		t.Errorf("list has %d items, want 10000", l.Len())
+	}
+}
+
+func TestAsyncListEmptyChannel(t *testing.T) {
+	items := make(chan interface{})
+	l, err := NewAsyncList(items, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !l.Loading() {
+		t.Errorf("list should be loading before the channel is closed")
+	}
+	close(items)
+
+	select {
+	case <-l.Update():
+	case <-time.After(time.Second):
+		t.Fatal("no update after the channel is closed")
+	}
+	if l.Loading() {
+		t.Errorf("list should not be loading after the channel is closed")
+	}
+	if l.Len() != 0 {
+		t.Errorf("list should be empty, got %d items", l.Len())
+	}
+}
+
+func TestAsyncListOrder(t *testing.T) {
+	items := make(chan interface{})
+	l, err := NewAsyncList(items, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for _, item := range []string{"c", "a", "d", "b"} {
+			items <- item
+		}
+		close(items)
+	}()
+	for l.Loading() {
+		<-l.Update()
+	}
+
+	l.SetCursor(2) // d
+	l.SetSort(func(a, b interface{}) bool { return a.(string) < b.(string) })
+	if visible, idx := l.Items(); strings.Join(toStrings(visible), "") != "abcd" || visible[idx] != "d" {
+		t.Errorf("sorted list is %v with %q selected, want abcd with d", visible, visible[idx])
+	}
+	l.SetSort(nil)
+	if visible, idx := l.Items(); strings.Join(toStrings(visible), "") != "cadb" || visible[idx] != "d" {
+		t.Errorf("unsorted list is %v with %q selected, want cadb with d", visible, visible[idx])
+	}
+	l.SetReverse(true)
+	if visible, idx := l.Items(); strings.Join(toStrings(visible), "") != "bdac" || visible[idx] != "d" {
+		t.Errorf("reversed list is %v with %q selected, want bdac with d", visible, visible[idx])
+	}
+}
+
+func TestAsyncListFilter(t *testing.T) {
+	items := make(chan interface{})
+	l, err := NewAsyncList(items, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Filter(func(item interface{}) bool { return item.(string) != "b" })
+	go func() {
+		for _, item := range []string{"a", "b", "c"} {
+			items <- item
+		}
+		close(items)
+	}()
+	for l.Loading() {
+		<-l.Update()
+	}
+	if visible, _ := l.Items(); strings.Join(toStrings(visible), "") != "ac" {
+		t.Errorf("filtered list is %v, want ac", visible)
+	}
+	if l.Total() != 3 {
+		t.Errorf("total is %d, want 3", l.Total())
+	}
+	l.Filter(nil)
+	if visible, _ := l.Items(); strings.Join(toStrings(visible), "") != "abc" {
+		t.Errorf("unfiltered list is %v, want abc", visible)
+	}
+}
+
+func TestNewAsyncListSize(t *testing.T) {
+	for _, size := range []int{-1, 0} {
+		if _, err := NewAsyncList(make(chan interface{}), size); err == nil {
+			t.Errorf("size %d should be rejected", size)
+		}
+	}
+	items := make(chan interface{})
+	l, err := NewAsyncList(items, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		items <- "a"
+		items <- "b"
+		close(items)
+	}()
+	for l.Loading() {
+		<-l.Update()
+	}
+	l.Next()
+	if visible, idx := l.Items(); len(visible) != 1 || visible[idx] != "b" {
+		t.Errorf("got %v with %d selected, want only b", visible, idx)
+	}
+}
+
+func toStrings(items []interface{}) []string {
+	strs := make([]string, len(items))
+	for i, item := range items {
+		strs[i] = item.(string)
+	}
+	return strs
+}
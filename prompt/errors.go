@@ -0,0 +1,24 @@
+package prompt
+
+import "fmt"
+
+// Error is the errors from the prompt package
+type Error string
+
+func (e Error) Error() string {
+	return string(e)
+}
+
+// ErrAborted is returned by Run if the user quits with ctrl-c or ctrl-d
+const ErrAborted Error = "aborted"
+
+// goroutinePanic is a panic of a goroutine of the prompt that is raised again
+// in the main loop, the stack of the goroutine would be lost otherwise
+type goroutinePanic struct {
+	value interface{}
+	stack []byte
+}
+
+func (e *goroutinePanic) Error() string {
+	return fmt.Sprintf("%v\n\ngoroutine stack:\n%s", e.value, e.stack)
+}
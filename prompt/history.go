@@ -0,0 +1,80 @@
+package prompt
+
+import (
+	"bufio"
+	"os"
+	"strings"
+)
+
+// history holds the committed search terms, it is persisted to a file so that
+// the terms can be recalled across the runs
+type history struct {
+	path    string
+	size    int
+	entries []string
+	pos     int    // index of the recalled entry, len(entries) if not recalling
+	draft   string // the input before the recall has started
+}
+
+// loadHistory reads the history file, a missing file yields an empty history
+func loadHistory(path string, size int) *history {
+	h := &history{path: path, size: size}
+	if f, err := os.Open(path); err == nil {
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			if line := scanner.Text(); len(line) > 0 {
+				h.entries = append(h.entries, line)
+			}
+		}
+	}
+	h.trim()
+	h.pos = len(h.entries)
+	return h
+}
+
+// add appends the term to the history and writes it to the file
+func (h *history) add(term string) error {
+	h.pos = len(h.entries)
+	term = strings.TrimSpace(term)
+	if len(term) == 0 {
+		return nil
+	}
+	if n := len(h.entries); n > 0 && h.entries[n-1] == term {
+		return nil
+	}
+	h.entries = append(h.entries, term)
+	h.trim()
+	h.pos = len(h.entries)
+	return os.WriteFile(h.path, []byte(strings.Join(h.entries, "\n")+"\n"), 0600)
+}
+
+// prev returns the previous entry, current is kept to be restored by next
+func (h *history) prev(current string) (string, bool) {
+	if h.pos == 0 {
+		return "", false
+	}
+	if h.pos == len(h.entries) {
+		h.draft = current
+	}
+	h.pos--
+	return h.entries[h.pos], true
+}
+
+// next returns the following entry or the draft if the recall is finished
+func (h *history) next() (string, bool) {
+	if h.pos >= len(h.entries) {
+		return "", false
+	}
+	h.pos++
+	if h.pos == len(h.entries) {
+		return h.draft, true
+	}
+	return h.entries[h.pos], true
+}
+
+func (h *history) trim() {
+	if h.size > 0 && len(h.entries) > h.size {
+		h.entries = h.entries[len(h.entries)-h.size:]
+	}
+}
@@ -0,0 +1,366 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/isacikgoz/gitin/term"
+)
+
+// action is a built-in behavior of the prompt that is triggered by a key
+type action int
+
+const (
+	actionPrev action = iota
+	actionNext
+	actionPageDown
+	actionPageUp
+	actionScrollLeft
+	actionScrollRight
+	actionToggleSearch
+	actionCancelSearch
+	actionCycleSearchMode
+	actionToggleHelp
+	actionToggleInfo
+	actionCycleSort
+	actionQuit
+	actionTop
+	actionBottom
+	actionFirst
+	actionLast
+)
+
+// actionDescs are displayed in the help, the actions sharing a description are
+// grouped together. The actions without a description are not displayed.
+var actionDescs = map[action]string{
+	actionPrev:            "navigation",
+	actionNext:            "navigation",
+	actionPageDown:        "navigation",
+	actionPageUp:          "navigation",
+	actionScrollLeft:      "scroll horizontally",
+	actionScrollRight:     "scroll horizontally",
+	actionToggleSearch:    "toggle search",
+	actionCancelSearch:    "cancel search",
+	actionCycleSearchMode: "cycle search mode",
+	actionToggleHelp:      "toggle help",
+	actionToggleInfo:      "fold/unfold information",
+	actionCycleSort:       "cycle sort order",
+	actionQuit:            "quit",
+	actionTop:             "jump to top/bottom",
+	actionBottom:          "jump to top/bottom",
+	actionFirst:           "jump to top/bottom",
+	actionLast:            "jump to top/bottom",
+}
+
+// motion returns true if the action can be repeated by a numeric prefix
+func (a action) motion() bool {
+	switch a {
+	case actionPrev, actionNext, actionPageDown, actionPageUp:
+		return true
+	}
+	return false
+}
+
+// Key is a key of the keyboard, it can be set from the environment as a single
+// character
+type Key rune
+
+// UnmarshalText parses the key from a single character
+func (k *Key) UnmarshalText(text []byte) error {
+	r, size := utf8.DecodeRune(text)
+	if size == 0 || size != len(text) || r == utf8.RuneError {
+		return fmt.Errorf("key %q must be a single character", text)
+	}
+	*k = Key(r)
+	return nil
+}
+
+// or returns the key, or def if the key is not set
+func (k Key) or(def rune) rune {
+	if k == 0 {
+		return def
+	}
+	return rune(k)
+}
+
+// builtinKey maps a key to a built-in action
+type builtinKey struct {
+	key    rune
+	then   rune // makes the key a chord like vim's gg, 0 for a single key
+	action action
+}
+
+// defaultKeymap returns the built-in keys in the order they are displayed
+func defaultKeymap(opts *Options) []builtinKey {
+	keymap := []builtinKey{
+		{key: term.ArrowDown, action: actionNext},
+		{key: term.ArrowUp, action: actionPrev},
+		{key: term.PageUp, action: actionPageUp},
+		{key: term.PageDown, action: actionPageDown},
+		{key: term.Home, action: actionFirst},
+		{key: term.End, action: actionLast},
+		{key: term.ArrowLeft, action: actionScrollLeft},
+		{key: term.ArrowRight, action: actionScrollRight},
+	}
+	if opts.VimKeys {
+		keymap = append(keymap,
+			builtinKey{key: 'j', action: actionNext},
+			builtinKey{key: 'k', action: actionPrev},
+			builtinKey{key: 'h', action: actionScrollLeft},
+			builtinKey{key: 'l', action: actionScrollRight},
+		)
+	}
+	keymap = append(keymap,
+		builtinKey{key: term.ShiftArrowLeft, action: actionScrollLeft},
+		builtinKey{key: term.ShiftArrowRight, action: actionScrollRight},
+		builtinKey{key: '/', action: actionToggleSearch},
+		builtinKey{key: rune(term.KeyESC), action: actionCancelSearch},
+		builtinKey{key: rune(term.KeyCtrlT), action: actionCycleSearchMode},
+		builtinKey{key: opts.HelpKey.or('?'), action: actionToggleHelp},
+		builtinKey{key: '\t', action: actionToggleInfo},
+		builtinKey{key: opts.QuitKey.or('q'), action: actionQuit},
+	)
+	if opts.VimKeys {
+		keymap = append(keymap,
+			builtinKey{key: 'g', then: 'g', action: actionTop},
+			builtinKey{key: 'G', action: actionBottom},
+		)
+	}
+	return keymap
+}
+
+// UnbindKey removes the built-in behavior of the key, e.g. to use j and k in a
+// key binding. It should be called before adding the key bindings.
+func (p *Prompt) UnbindKey(key rune) {
+	keymap := p.keymap[:0]
+	for _, bk := range p.keymap {
+		if bk.key != key {
+			keymap = append(keymap, bk)
+		}
+	}
+	p.keymap = keymap
+}
+
+// RemapKey moves the built-in behavior of a key to another one. It returns an
+// error if the key has no built-in behavior or the new key is already in use.
+func (p *Prompt) RemapKey(from, to rune) error {
+	if p.mapped(to) || p.bound(to) {
+		return fmt.Errorf("key %q is already in use", to)
+	}
+	var found bool
+	for i := range p.keymap {
+		if p.keymap[i].key == from {
+			p.keymap[i].key = to
+			found = true
+		}
+		if p.keymap[i].then == from {
+			p.keymap[i].then = to
+		}
+	}
+	if !found {
+		return fmt.Errorf("key %q has no built-in behavior", from)
+	}
+	return nil
+}
+
+// builtin returns the built-in action of the key
+func (p *Prompt) builtin(key rune) (action, bool) {
+	return p.builtinChord(key, 0)
+}
+
+// builtinChord returns the built-in action of the two keys, then is 0 for the
+// actions of a single key
+func (p *Prompt) builtinChord(key, then rune) (action, bool) {
+	for _, bk := range p.keymap {
+		if bk.key == key && bk.then == then {
+			return bk.action, true
+		}
+	}
+	return 0, false
+}
+
+// mapped returns true if the key has a built-in action, alone or as the first
+// key of a chord
+func (p *Prompt) mapped(key rune) bool {
+	for _, bk := range p.keymap {
+		if bk.key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// bound returns true if the key is used by a key binding
+func (p *Prompt) bound(key rune) bool {
+	for _, kb := range p.keyBindings {
+		if kb.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// runAction applies the built-in action
+func (p *Prompt) runAction(a action) {
+	switch a {
+	case actionPrev:
+		p.list.Prev()
+	case actionNext:
+		p.list.Next()
+	case actionPageDown:
+		p.list.PageDown()
+	case actionPageUp:
+		p.list.PageUp()
+	case actionScrollLeft:
+		if p.hoffset -= hscrollStep; p.hoffset < 0 {
+			p.hoffset = 0
+		}
+	case actionScrollRight:
+		p.hoffset += hscrollStep
+	case actionToggleSearch:
+		if p.inputMode {
+			p.flushSearch()
+			p.commitSearch()
+		}
+		p.inputMode = !p.inputMode
+	case actionCancelSearch:
+		if p.inputMode {
+			p.cancelSearch()
+		}
+	case actionCycleSearchMode:
+		p.flushSearch()
+		p.searchMode = p.searchMode.next()
+		p.configureList()
+	case actionToggleHelp:
+		p.helpMode = !p.helpMode
+	case actionToggleInfo:
+		p.infoFolded = !p.infoFolded
+	case actionCycleSort:
+		p.cycleSort()
+	case actionTop, actionFirst:
+		p.list.SetCursor(0)
+		p.list.SetStart(0)
+	case actionBottom, actionLast:
+		p.list.SetCursor(p.list.Len() - 1)
+	}
+}
+
+// cycleSort applies the next sort order to the list
+func (p *Prompt) cycleSort() {
+	l, ok := p.list.(interface {
+		SetSort(func(a, b interface{}) bool)
+	})
+	if !ok {
+		return
+	}
+	p.sortOrder = (p.sortOrder + 1) % (len(p.sortOrders) + 1)
+	l.SetSort(p.sortLess())
+}
+
+// sortLess returns the function of the applied sort order, nil for the
+// original order
+func (p *Prompt) sortLess() func(a, b interface{}) bool {
+	if p.sortOrder == 0 {
+		return nil
+	}
+	return p.sortOrders[p.sortOrder-1].Less
+}
+
+// sortName returns the name of the applied sort order
+func (p *Prompt) sortName() string {
+	if p.sortOrder == 0 {
+		return "original"
+	}
+	return p.sortOrders[p.sortOrder-1].Name
+}
+
+// control is a line of the help, the keys share the description
+type control struct {
+	keys     string
+	desc     string
+	category string
+	disabled bool // the binding doesn't apply to the selected item
+}
+
+// generalCategory is the help section of the controls without a category, it
+// is listed after the other sections
+const generalCategory = "General"
+
+// actionCategory returns the help section of the built-in action
+func actionCategory(a action) string {
+	switch a {
+	case actionPrev, actionNext, actionPageDown, actionPageUp, actionScrollLeft,
+		actionScrollRight, actionTop, actionBottom, actionFirst, actionLast:
+		return "Navigation"
+	case actionToggleSearch, actionCancelSearch, actionCycleSearchMode:
+		return "Search"
+	}
+	return generalCategory
+}
+
+// builtinControls returns the help of the built-in keys in the keymap order
+func (p *Prompt) builtinControls() []control {
+	var descs []string
+	keys := make(map[string][]string)
+	categories := make(map[string]string)
+	for _, bk := range p.keymap {
+		desc, ok := actionDescs[bk.action]
+		if !ok {
+			continue
+		}
+		if bk.action == actionCycleSort {
+			desc += " (" + p.sortName() + ")"
+		}
+		if _, ok := keys[desc]; !ok {
+			descs = append(descs, desc)
+			categories[desc] = actionCategory(bk.action)
+		}
+		name := keyName(bk.key)
+		if bk.then != 0 {
+			name += keyName(bk.then)
+		}
+		keys[desc] = append(keys[desc], name)
+	}
+	controls := make([]control, len(descs))
+	for i, desc := range descs {
+		controls[i] = control{keys: strings.Join(keys[desc], " "), desc: desc, category: categories[desc]}
+	}
+	return controls
+}
+
+// keyName returns the displayed name of the key
+func keyName(key rune) string {
+	switch key {
+	case term.ArrowLeft:
+		return "←"
+	case term.ArrowRight:
+		return "→"
+	case term.ArrowUp:
+		return "↑"
+	case term.ArrowDown:
+		return "↓"
+	case term.ShiftArrowLeft:
+		return "shift ←"
+	case term.ShiftArrowRight:
+		return "shift →"
+	case term.Home:
+		return "home"
+	case term.End:
+		return "end"
+	case term.PageUp:
+		return "pgup"
+	case term.PageDown:
+		return "pgdn"
+	case rune(term.KeyESC):
+		return "esc"
+	case ' ':
+		return "space"
+	case '\t':
+		return "tab"
+	}
+	if key < ' ' {
+		return "ctrl-" + string(key+'a'-1)
+	}
+	return string(key)
+}
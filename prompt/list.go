@@ -1,5 +1,10 @@
 package prompt
 
+import "reflect"
+
+// List is the collection the prompt navigates and searches. SyncList and
+// AsyncList are the bundled implementations, a custom source can be passed to
+// Create as long as it satisfies this interface.
 type List interface {
 	// Next moves the visible list forward one item
 	Next()
@@ -53,5 +58,98 @@ type List interface {
 	// Size is the number of items to be displayed
 	Size() int
 
+	// Len is the number of items in the current (possibly searched) scope
+	Len() int
+
+	// Loading reports whether the list is still receiving its items
+	Loading() bool
+
+	// Update notifies the prompt that the items have changed and it should be
+	// rendered again. A list with a fixed set of items may return nil.
 	Update() chan struct{}
 }
+
+// Header is implemented by the items that label a section of the list, like
+// the staged files of the status. They are rendered like the other items but
+// they can't be selected and they are left out of the search results.
+type Header interface {
+	Header() bool
+}
+
+// isHeader returns true if the item is a header
+func isHeader(item interface{}) bool {
+	h, ok := item.(Header)
+	return ok && h.Header()
+}
+
+// Identifiable is implemented by the items that are created again when the
+// list is reloaded, the items with the same identity are the same logical item
+// so that the selection stays on it, e.g. a file with the same path
+type Identifiable interface {
+	Identity() string
+}
+
+// sameItem returns true if the items have the same identity, or if they are
+// equal if they aren't Identifiable
+func sameItem(a, b interface{}) bool {
+	if x, ok := a.(Identifiable); ok {
+		y, ok := b.(Identifiable)
+		return ok && x.Identity() == y.Identity()
+	}
+	if a == nil || b == nil || !reflect.TypeOf(a).Comparable() {
+		return false
+	}
+	return a == b
+}
+
+// countItems returns the number of the items that aren't headers
+func countItems(items []interface{}) int {
+	var n int
+	for _, item := range items {
+		if !isHeader(item) {
+			n++
+		}
+	}
+	return n
+}
+
+// filterItems returns the items that filter returns true for, a header is kept
+// if any item of its section is. The items are returned as is if filter is nil.
+func filterItems(items []interface{}, filter func(interface{}) bool) []interface{} {
+	if filter == nil {
+		return items
+	}
+	filtered := make([]interface{}, 0)
+	var header interface{}
+	for _, item := range items {
+		if isHeader(item) {
+			header = item
+			continue
+		}
+		if !filter(item) {
+			continue
+		}
+		if header != nil {
+			filtered = append(filtered, header)
+			header = nil
+		}
+		filtered = append(filtered, item)
+	}
+	return filtered
+}
+
+// nextItem returns the index of the first item that isn't a header starting
+// from i in the direction d, or NotFound if there is none
+func nextItem(scope []interface{}, i, d int) int {
+	for ; i >= 0 && i < len(scope); i += d {
+		if !isHeader(scope[i]) {
+			return i
+		}
+	}
+	return NotFound
+}
+
+var (
+	_ List = (*SyncList)(nil)
+	_ List = (*AsyncList)(nil)
+)
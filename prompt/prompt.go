@@ -2,43 +2,151 @@ package prompt
 
 import (
 	"context"
+	"encoding"
+	"fmt"
+	"io"
 	"os"
-	"os/signal"
+	"reflect"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
-	"syscall"
 	"time"
+	"unicode"
 	"unicode/utf8"
 
 	"github.com/fatih/color"
 	"github.com/isacikgoz/gitin/term"
+	"github.com/mattn/go-runewidth"
 )
 
+// hscrollStep is the number of columns to shift at each horizontal scroll
+const hscrollStep = 8
+
+// spinnerInterval is the time between the frames of the loading spinner
+const spinnerInterval = 100 * time.Millisecond
+
+// DefaultLineSize is a size to create the lists with if the LineSize option is
+// not set, the prompt fits them to the terminal when it runs
+const DefaultLineSize = 5
+
+// chordTimeout is the default interval that the second key of a chord like
+// "gg" is waited for
+const chordTimeout = 500 * time.Millisecond
+
 type keyEvent struct {
-	ch  rune
-	err error
+	ch    rune
+	paste string // the pasted text if ch is term.PasteStart
+	err   error
+}
+
+// SortOrder is an order the items can be listed in, see WithSortOrders
+type SortOrder struct {
+	Name string
+	Less func(a, b interface{}) bool
 }
 
 // KeyBinding is used for mapping a key to a function
 type KeyBinding struct {
-	Key     rune
-	Display string
-	Handler func(interface{}) error
-	Desc    string
+	Key      rune
+	Display  string
+	Handler  func(interface{}) error
+	Desc     string
+	Primary  bool   // listed in the hints below the prompt, see the Hints option
+	Category string // the section of the help, "General" if it is empty
+
+	// DescFunc describes the effect of the binding on the selected item in the
+	// hints, e.g. add or reset a file depending on its state. Desc is used if
+	// it is nil.
+	DescFunc func(interface{}) string
+
+	// Enabled tells if the binding applies to the selected item, the key is
+	// ignored otherwise and the binding is grayed out in the help. A nil
+	// Enabled applies to every item.
+	Enabled func(interface{}) bool
+
+	// Then makes the binding a chord like vim's dd, the handler runs if Then is
+	// pressed within Timeout after Key. A binding of Key alone runs if no other
+	// key follows in time.
+	Then    rune
+	Timeout time.Duration // defaults to 500ms
 }
 
 type selectionHandlerFunc func(interface{}) error
+
+// itemRendererFunc renders the item, the matches are the byte offsets of the
+// matched runes in the searched string of the item, see term.Highlight
 type itemRendererFunc func(interface{}, []int, bool) [][]term.Cell
 type informationRendererFunc func(interface{}) [][]term.Cell
 
 // OptionalFunc handles functional arguments of the prompt
 type OptionalFunc func(*Prompt)
 
-// Options is the common options for building a prompt
+// Options is the common options for building a prompt. None of them is
+// required, the zero value of a field disables its feature and the default
+// tags are applied by DefaultOptions or by envconfig from the environment.
 type Options struct {
-	LineSize      int `default:"5"`
-	StartInSearch bool
-	DisableColor  bool
-	VimKeys       bool `default:"true"`
+	LineSize         int // caps the visible items, 0 fits the list to the terminal
+	StartInSearch    bool
+	DisableColor     bool
+	DisableClipboard bool // some terminals flash or ask for permission when the clipboard is set
+	VimKeys          bool `default:"true"`
+	WrapScroll       bool
+	HistoryFile      string
+	HistorySize      int           `default:"100"`
+	SearchDelay      time.Duration `default:"80ms"`
+	SearchMode       SearchMode
+	MinScore         int  // the fuzzy matches scoring below are dropped, 0 keeps all of them
+	KeepOrder        bool // the matches are listed in the order of the items instead of by their scores
+	AltScreen        bool
+	BlinkCursor      bool `default:"true"`
+	QuitKey          Key  `default:"q"`
+	HelpKey          Key  `default:"?"`
+	Reverse          bool // list the items in the reverse order, e.g. the oldest commits first
+	Hints            bool // the primary key bindings are listed below the prompt
+	SortHelp         bool // the help lists the controls by their descriptions instead of the order they are added
+}
+
+// DefaultOptions returns the options set to their default tags
+func DefaultOptions() *Options {
+	opts := &Options{}
+	v := reflect.ValueOf(opts).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		def, ok := v.Type().Field(i).Tag.Lookup("default")
+		if !ok {
+			continue
+		}
+		if err := setDefault(v.Field(i), def); err != nil {
+			panic(fmt.Sprintf("default of %s: %v", v.Type().Field(i).Name, err))
+		}
+	}
+	return opts
+}
+
+// setDefault parses the default tag into the field of the options
+func setDefault(field reflect.Value, def string) error {
+	if u, ok := field.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(def))
+	}
+	switch field.Interface().(type) {
+	case time.Duration:
+		d, err := time.ParseDuration(def)
+		field.SetInt(int64(d))
+		return err
+	case bool:
+		b, err := strconv.ParseBool(def)
+		field.SetBool(b)
+		return err
+	case int:
+		n, err := strconv.Atoi(def)
+		field.SetInt(int64(n))
+		return err
+	case string:
+		field.SetString(def)
+		return nil
+	}
+	return fmt.Errorf("unsupported type %s", field.Type())
 }
 
 // State holds the changeable vars of the prompt
@@ -50,6 +158,12 @@ type State struct {
 	Cursor      int
 	Scroll      int
 	ListSize    int
+	Matched     int // the number of the items in the search results
+	Total       int // the number of all of the items
+
+	// Selected is the item under the cursor, SetState moves the cursor to the
+	// same item in the list if it is Identifiable, otherwise Cursor is used
+	Selected interface{}
 }
 
 // Prompt is a interactive prompt for command-line
@@ -57,48 +171,194 @@ type Prompt struct {
 	list        List
 	opts        *Options
 	keyBindings []*KeyBinding
+	keymap      []builtinKey
 
 	selectionHandler    selectionHandlerFunc
+	quitHandler         selectionHandlerFunc
 	itemRenderer        itemRendererFunc
 	informationRenderer informationRendererFunc
+	theme               *Theme
+	emptyMessage        []term.Cell // displayed if nothing matches the search
 
 	exitMsg [][]term.Cell // to be set on runtime if required
 
+	info       [][]term.Cell // cached information of the selected item
+	infoItem   interface{}
+	infoValid  bool
+	infoFolded bool     // only the summary of the information is displayed
+	history    *history // nil unless a history file is set
+
 	inputMode  bool
 	helpMode   bool
 	itemsLabel string
 	input      string
+	caret      int         // position of the input cursor in runes
+	inputLine  []term.Cell // replaces the search line while Input is reading
+	searchMode SearchMode
+
+	searchTimer   *time.Timer // debounces the search while typing
+	searchPending bool
+	hoffset       int // horizontal scroll offset of the items in columns
 
-	reader *term.RuneReader     // initialized by prompt
-	writer *term.BufferedWriter // initialized by prompt
-	mx     *sync.RWMutex
+	chordKey   rune // the first key of a chord waiting for the second one
+	chordTimer *time.Timer
+	count      int // numeric prefix of the next motion, like vim's 5j
+
+	message      []term.Cell // displayed below the list until a key is pressed
+	messageTimer *time.Timer // clears the message if it has a timeout
+
+	sortOrders []SortOrder
+	sortOrder  int  // 1-based index of the applied order, 0 for the original one
+	reverse    bool // initialized by the Reverse option
+	searchKey  func(interface{}) string
+	equal      func(a, b interface{}) bool // nil compares the items with sameItem
+	filter     func(interface{}) bool      // set by Filter, nil lists all of the items
+
+	spinner int // frame of the loading spinner
+
+	reader   *term.RuneReader     // initialized by prompt
+	writer   *term.BufferedWriter // initialized by prompt
+	headless bool                 // set if the reader and the writer are not the terminal
+	mx       *sync.RWMutex
 
 	events  chan keyEvent
 	quit    chan struct{}
 	newItem chan struct{}
+
+	aborted   bool        // set if the user quits with ctrl-c or ctrl-d
+	selecting bool        // set by RunSelect, enter picks the item
+	selected  interface{} // the picked item, nil if the prompt is aborted
+
+	postMx sync.Mutex
+	posts  []func()
+	fed    []rune // the keys queued by Feed
+	posted chan struct{}
+
+	jobs []func(ctx context.Context) // run in the background while the prompt runs
 }
 
-// Create returns a pointer to prompt that is ready to Run
+// Create returns a pointer to prompt that is ready to Run, the default options
+// are used if opts is nil
 func Create(label string, opts *Options, list List, fs ...OptionalFunc) *Prompt {
+	if opts == nil {
+		opts = DefaultOptions()
+	}
 	p := &Prompt{
-		opts:         opts,
-		list:         list,
-		itemsLabel:   label,
-		itemRenderer: itemText,
-		reader:       term.NewRuneReader(os.Stdin),
-		writer:       term.NewBufferedWriter(os.Stdout),
-		mx:           &sync.RWMutex{},
-		events:       make(chan keyEvent, 20),
-		quit:         make(chan struct{}, 1),
-		newItem:      make(chan struct{}),
+		opts:       opts,
+		list:       list,
+		itemsLabel: label,
+		theme:      DefaultTheme(),
+		searchMode: opts.SearchMode,
+		reverse:    opts.Reverse,
+		reader:     term.NewRuneReader(os.Stdin),
+		writer:     term.NewBufferedWriter(os.Stdout),
+		mx:         &sync.RWMutex{},
+		events:     make(chan keyEvent, 20),
+		quit:       make(chan struct{}, 1),
+		newItem:    make(chan struct{}),
+		posted:     make(chan struct{}, 1),
+	}
+	p.itemRenderer = p.itemText
+	p.emptyMessage = term.Cprint("Not found.", color.FgRed)
+	p.informationRenderer = func(interface{}) [][]term.Cell { return nil }
+	p.keymap = defaultKeymap(opts)
+	p.quitHandler = func(interface{}) error {
+		p.Stop()
+		return nil
+	}
+	if len(opts.HistoryFile) > 0 {
+		p.history = loadHistory(opts.HistoryFile, opts.HistorySize)
 	}
 
 	for _, f := range fs {
 		f(p)
 	}
+	p.configureList()
 	return p
 }
 
+// configureList applies the list related options to the current list if the
+// list implementation supports them
+func (p *Prompt) configureList() {
+	if l, ok := p.list.(interface{ SetSize(int) }); ok {
+		if size := p.fitLineSize(); size > 0 {
+			l.SetSize(size)
+		}
+	}
+	if l, ok := p.list.(interface{ SetWrapScroll(bool) }); ok {
+		l.SetWrapScroll(p.opts.WrapScroll)
+	}
+	if l, ok := p.list.(interface{ SetSearchMode(SearchMode) }); ok {
+		l.SetSearchMode(p.searchMode)
+	}
+	if l, ok := p.list.(interface{ SetScoring(int, bool) }); ok {
+		l.SetScoring(p.opts.MinScore, p.opts.KeepOrder)
+	}
+	if l, ok := p.list.(interface {
+		SetSort(func(a, b interface{}) bool)
+	}); ok && p.sortOrder > 0 {
+		l.SetSort(p.sortLess())
+	}
+	if l, ok := p.list.(interface{ SetReverse(bool) }); ok {
+		l.SetReverse(p.reverse)
+	}
+	if l, ok := p.list.(interface {
+		SetSearchKey(func(interface{}) string)
+	}); ok && p.searchKey != nil {
+		l.SetSearchKey(p.searchKey)
+	}
+	if l, ok := p.list.(interface {
+		SetEqual(func(a, b interface{}) bool)
+	}); ok && p.equal != nil {
+		l.SetEqual(p.equal)
+	}
+}
+
+// Reverse toggles the order of the items, the replacing lists are reversed as
+// well. The list must have a SetReverse method like SyncList and AsyncList.
+func (p *Prompt) Reverse() {
+	p.reverse = !p.reverse
+	if l, ok := p.list.(interface{ SetReverse(bool) }); ok {
+		l.SetReverse(p.reverse)
+	}
+}
+
+// Filter narrows the list to the items that filter returns true for, a nil
+// filter lists all of them again. The replacing lists are filtered as well, so
+// filter should return true for the items it doesn't know about. The list must
+// have a Filter method like SyncList and AsyncList.
+func (p *Prompt) Filter(filter func(interface{}) bool) {
+	p.filter = filter
+	if l, ok := p.list.(interface{ Filter(func(interface{}) bool) }); ok {
+		l.Filter(filter)
+	}
+}
+
+// fitLineSize returns the number of the items that fit to the terminal, capped
+// by the LineSize option. A third of the rows is left for the information. It
+// returns the option if the terminal size is not known.
+func (p *Prompt) fitLineSize() int {
+	_, height, err := term.Size()
+	if err != nil {
+		return p.opts.LineSize
+	}
+	return lineSizeFor(height, p.opts.LineSize)
+}
+
+// lineSizeFor returns the number of the items that fit to the terminal height,
+// the size is at most max unless it is 0
+func lineSizeFor(height, max int) int {
+	// the search line and the empty line before the information are excluded
+	size := (height - 2) * 2 / 3
+	if size < 1 {
+		size = 1
+	}
+	if max > 0 && max < size {
+		size = max
+	}
+	return size
+}
+
 // WithSelectionHandler adds a selection handler to the prompt
 func WithSelectionHandler(f selectionHandlerFunc) OptionalFunc {
 	return func(p *Prompt) {
@@ -106,6 +366,62 @@ func WithSelectionHandler(f selectionHandlerFunc) OptionalFunc {
 	}
 }
 
+// WithSearchKey makes the search match the items by the text that key returns
+// instead of fmt.Sprint, e.g. to match a field while a longer line is rendered.
+// The matches passed to the item renderer are the offsets in the key. The key
+// is applied to the replacing lists as well, so it should format the items it
+// doesn't know with fmt.Sprint. The list must have a SetSearchKey method like
+// SyncList and AsyncList.
+func WithSearchKey(key func(item interface{}) string) OptionalFunc {
+	return func(p *Prompt) {
+		p.searchKey = key
+	}
+}
+
+// WithEqual defines when two items are the same item, so that the selection
+// is kept when the list is searched or replaced by SetState, e.g. to compare
+// the value items created again by their paths. The items are compared with
+// their Identity if they are Identifiable, or with == otherwise. It is applied
+// to the replacing lists if they have a SetEqual method like SyncList and
+// AsyncList.
+func WithEqual(equal func(a, b interface{}) bool) OptionalFunc {
+	return func(p *Prompt) {
+		p.equal = equal
+	}
+}
+
+// WithQuitHandler replaces the behavior of the quit key, which stops the prompt
+// by default. The handler is called with the selected item, or nil if there is
+// none, e.g. to go back from a sub-list instead of quitting.
+func WithQuitHandler(f selectionHandlerFunc) OptionalFunc {
+	return func(p *Prompt) {
+		p.quitHandler = f
+	}
+}
+
+// WithIO replaces the stdin and the stdout of the prompt, the terminal is not
+// initialized in that case. It is meant to drive the prompt with scripted keys
+// and inspect the output, e.g. in tests. The prompt stops reading the keys at
+// the end of the input but keeps running until it is quit.
+func WithIO(in io.Reader, out io.Writer) OptionalFunc {
+	return func(p *Prompt) {
+		p.reader = term.NewRuneReader(in)
+		p.writer = term.NewBufferedWriter(out)
+		p.headless = true
+	}
+}
+
+// WithSortOrders lets the user cycle the items through the orders with the o
+// key, the items are listed in their original order after the last one. The
+// list must have a SetSort method like SyncList and AsyncList. The order is kept
+// when the list is replaced, so Less should ignore the items it doesn't know.
+func WithSortOrders(orders ...SortOrder) OptionalFunc {
+	return func(p *Prompt) {
+		p.sortOrders = orders
+		p.keymap = append(p.keymap, builtinKey{key: 'o', action: actionCycleSort})
+	}
+}
+
 // WithItemRenderer to add your own implementation on rendering an Item
 func WithItemRenderer(f itemRendererFunc) OptionalFunc {
 	return func(p *Prompt) {
@@ -113,6 +429,22 @@ func WithItemRenderer(f itemRendererFunc) OptionalFunc {
 	}
 }
 
+// WithEmptyMessage replaces the "Not found." message that is displayed in place
+// of the list if nothing matches the search, a plain message can be given with
+// term.Cprint
+func WithEmptyMessage(msg []term.Cell) OptionalFunc {
+	return func(p *Prompt) {
+		p.emptyMessage = msg
+	}
+}
+
+// WithTheme replaces the attributes of the default item renderer
+func WithTheme(t *Theme) OptionalFunc {
+	return func(p *Prompt) {
+		p.theme = t
+	}
+}
+
 // WithInformation adds additional information below to the prompt
 func WithInformation(f informationRendererFunc) OptionalFunc {
 	return func(p *Prompt) {
@@ -122,27 +454,68 @@ func WithInformation(f informationRendererFunc) OptionalFunc {
 
 // Run as name implies starts the prompt until it quits
 func (p *Prompt) Run(ctx context.Context) error {
-	// disable echo and hide cursor
-	if err := term.Init(os.Stdin, os.Stdout); err != nil {
-		return err
+	var closed bool
+	if p.headless {
+		closed = true // nothing to restore
+	} else {
+		if p.opts.AltScreen {
+			term.EnableAltScreen()
+		}
+		// disable echo and hide cursor
+		if err := term.Init(os.Stdin, os.Stdout); err != nil {
+			return err
+		}
+	}
+	closeTerm := func() {
+		if !closed {
+			closed = true
+			_ = term.Close()
+		}
 	}
-	defer term.Close()
+	defer closeTerm()
+	// the terminal is restored before the program crashes, otherwise the shell
+	// would be left without the echo and the cursor
+	defer func() {
+		if r := recover(); r != nil {
+			p.writer.Reset()
+			_ = p.writer.ClearScreen()
+			closeTerm()
+			panic(r)
+		}
+	}()
 
 	if p.opts.DisableColor {
 		term.DisableColor()
 	}
+	if p.opts.DisableClipboard {
+		term.DisableClipboard()
+	}
 
 	if p.opts.StartInSearch {
 		p.inputMode = true
 	}
+	p.aborted = false
+	p.configureList() // the list is fit to the terminal once it is initialized
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 	// start input loop
-	go p.spawnEvents(ctx)
+	go func() {
+		defer p.forwardPanic()
+		p.spawnEvents(ctx)
+	}()
+	for _, job := range p.jobs {
+		go func(job func(context.Context)) {
+			defer p.forwardPanic()
+			job(ctx)
+		}(job)
+	}
 
 	p.render() // start with an initial render
 
-	err := p.mainloop()
+	err := p.mainloop(ctx)
+	// the input loop is stopped even if it is waiting for a key
+	cancel()
+	p.reader.Cancel()
 
 	// reset cursor position and remove buffer
 	p.writer.Reset()
@@ -151,7 +524,12 @@ func (p *Prompt) Run(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	if p.aborted {
+		return ErrAborted
+	}
 
+	// leave the alternate screen first, otherwise the message would be lost
+	closeTerm()
 	for _, cells := range p.exitMsg {
 		_, _ = p.writer.WriteCells(cells)
 	}
@@ -160,73 +538,217 @@ func (p *Prompt) Run(ctx context.Context) error {
 	return nil
 }
 
+// RunSelect starts the prompt like Run, but enter picks the item instead of
+// calling the selection handler and stops the prompt. It returns the picked item
+// and true, or false if the prompt is quit in another way. Like Run, the error
+// is ErrAborted if the user quits with ctrl-c.
+func (p *Prompt) RunSelect(ctx context.Context) (interface{}, bool, error) {
+	p.selecting = true
+	p.selected = nil
+	defer func() { p.selecting = false }()
+	if err := p.Run(ctx); err != nil {
+		return nil, false, err
+	}
+	return p.selected, p.selected != nil, nil
+}
+
+// Print writes all of the items to w without running the prompt, e.g. when the
+// output is piped to another program. It waits until the list is loaded, the
+// items are rendered unselected and the blank of the cursor is trimmed.
+func (p *Prompt) Print(ctx context.Context, w io.Writer) error {
+	if p.opts.DisableColor {
+		term.DisableColor()
+	}
+	for p.list.Loading() {
+		select {
+		case <-p.list.Update():
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if l, ok := p.list.(interface{ SetSize(int) }); ok {
+		l.SetSize(p.list.Len())
+	}
+	p.list.SetStart(0)
+	items, _ := p.list.Items()
+	for _, item := range items {
+		for _, line := range p.itemRenderer(item, nil, false) {
+			for len(line) > 0 && line[0].Ch == ' ' {
+				line = line[1:]
+			}
+			if _, err := fmt.Fprintln(w, term.Sprint(line)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 // Stop sends a quit signal to the main loop of the prompt
 func (p *Prompt) Stop() {
 	p.quit <- struct{}{}
 }
 
+// forwardPanic recovers the panic of a goroutine and raises it again in the
+// main loop, so that Run can restore the terminal. It must be deferred.
+func (p *Prompt) forwardPanic() {
+	if r := recover(); r != nil {
+		gp := &goroutinePanic{value: r, stack: debug.Stack()}
+		p.Post(func() { panic(gp) })
+	}
+}
+
+// spawnEvents reads the keys and sends them to the main loop. The input is
+// waited for without the lock, and read with it, so that a handler reading the
+// keys itself, or running a program like less, isn't robbed of its input.
 func (p *Prompt) spawnEvents(ctx context.Context) {
 	for {
-		select {
-		case <-ctx.Done():
+		p.reader.Wait()
+		p.mx.Lock()
+		if ctx.Err() != nil {
+			p.mx.Unlock()
 			return
-		case <-time.After(10 * time.Millisecond):
-			p.mx.Lock()
-			r, _, err := p.reader.ReadRune()
+		}
+		if !p.reader.Ready() {
+			// the input is consumed by a handler in the meantime
 			p.mx.Unlock()
-			p.events <- keyEvent{ch: r, err: err}
+			continue
+		}
+		ev := keyEvent{}
+		ev.ch, _, ev.err = p.reader.ReadRune()
+		if ev.err == nil && ev.ch == term.PasteStart {
+			ev.paste, ev.err = p.reader.ReadPaste()
+		}
+		p.mx.Unlock()
+		if ev.err == io.EOF && p.headless {
+			return // the scripted keys are consumed
+		}
+		select {
+		case p.events <- ev:
+		case <-ctx.Done():
+			return // the main loop is not receiving anymore
 		}
 	}
 }
 
 // this is the main loop for reading input channel
-func (p *Prompt) mainloop() error {
-	sigwinch := make(chan os.Signal, 1)
-	defer close(sigwinch)
-	signal.Notify(sigwinch, syscall.SIGWINCH)
+func (p *Prompt) mainloop(ctx context.Context) error {
+	resized := notifyResize(ctx)
+	ticker := time.NewTicker(spinnerInterval)
+	defer ticker.Stop()
 
 	for {
+		// animate the spinner only while the list is loading
+		var spin <-chan time.Time
+		if p.list.Loading() {
+			spin = ticker.C
+		}
 		select {
 		case <-p.quit:
 			return nil
-		case <-sigwinch:
+		case <-resized:
+			p.mx.Lock()
+			p.configureList()
+			p.mx.Unlock()
+			p.render()
+		case <-spin:
+			p.spinner++
 			p.render()
 		case <-p.list.Update():
 			p.render()
-		case ev := <-p.events:
-			if err := func() error {
-				p.mx.Lock()
-				defer p.mx.Unlock()
-
-				if err := ev.err; err != nil {
+		case <-p.posted:
+			p.runPosts()
+			p.render()
+			if key, ok := p.nextFed(); ok {
+				if err := p.onEvent(keyEvent{ch: key}); err != nil {
 					return err
 				}
+			}
+		case <-p.chordC():
+			p.mx.Lock()
+			err := p.flushChord()
+			p.mx.Unlock()
+			if err != nil {
+				return err
+			}
+			p.render()
+		case <-p.messageC():
+			p.mx.Lock()
+			p.clearMessage()
+			p.mx.Unlock()
+			p.render()
+		case <-p.searchC():
+			p.mx.Lock()
+			p.flushSearch()
+			p.mx.Unlock()
+			p.render()
+		case ev := <-p.events:
+			if err := p.onEvent(ev); err != nil {
+				return err
+			}
+		}
+	}
+}
 
-				switch r := ev.ch; r {
-				case rune(term.KeyCtrlC), rune(term.KeyCtrlD):
-					p.Stop()
-					return nil
-				case term.Enter, term.NewLine:
-					items, idx := p.list.Items()
-					if idx == NotFound {
-						break
-					}
-
-					if err := p.selectionHandler(items[idx]); err != nil {
-						return err
-					}
-				default:
-					if err := p.onKey(r); err != nil {
-						return err
-					}
-				}
-				p.render()
-				return nil
-			}(); err != nil {
+// onEvent handles a key read from the input or fed to the prompt
+func (p *Prompt) onEvent(ev keyEvent) error {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	if err := ev.err; err != nil {
+		return err
+	}
+
+	switch r := ev.ch; r {
+	case rune(term.KeyCtrlC), rune(term.KeyCtrlD):
+		p.aborted = true
+		p.Stop()
+		return nil
+	case term.PasteStart, term.Enter, term.NewLine:
+		// the pending key runs its own binding like before any other key
+		if p.chordKey != 0 {
+			if err := p.flushChord(); err != nil {
 				return err
 			}
 		}
 	}
+	switch r := ev.ch; r {
+	case term.PasteStart:
+		// the pasted text never triggers the key bindings
+		if p.inputMode {
+			p.insertInput(ev.paste)
+		}
+	case term.Enter, term.NewLine:
+		if p.inputMode {
+			p.flushSearch()
+			p.commitSearch()
+		}
+		items, idx := p.list.Items()
+		if idx == NotFound {
+			break
+		}
+
+		if p.selecting {
+			p.selected = items[idx]
+			p.Stop()
+			return nil
+		}
+		if err := p.selectionHandler(items[idx]); err != nil {
+			return err
+		}
+	default:
+		if err := p.onKey(r); err != nil {
+			return err
+		}
+	}
+	p.render()
+	return nil
+}
+
+// itemText is the default item renderer, it reads the theme on each call so
+// that the order of the optional funcs doesn't matter
+func (p *Prompt) itemText(item interface{}, matches []int, selected bool) [][]term.Cell {
+	return p.theme.itemText(item, matches, selected)
 }
 
 // render function draws screen's list to terminal
@@ -244,98 +766,709 @@ func (p *Prompt) render() {
 	}
 
 	items, idx := p.list.Items()
-	_, _ = p.writer.WriteCells(renderSearch(p.itemsLabel, p.inputMode, p.input))
+	total := p.list.Len()
+	if p.inputLine != nil {
+		_, _ = p.writer.WriteCells(p.inputLine)
+	} else {
+		cells := renderSearch(p.itemsLabel, p.searchMode, p.inputMode, p.input, p.caret, p.opts.BlinkCursor)
+		if pos, matched := listPosition(p.list); matched > 0 {
+			counter := fmt.Sprintf(" %d/%d", pos, matched)
+			if all := listTotal(p.list); all > matched {
+				counter += fmt.Sprintf(" of %d", all)
+			}
+			if page, pages := pageOf(p.list.Start(), p.list.Size(), total); pages > 1 {
+				counter += fmt.Sprintf(" page %d/%d", page, pages)
+			}
+			cells = append(cells, term.Cprint(counter, color.Faint)...)
+		}
+		if p.list.Loading() {
+			cells = append(cells, term.Cprint(" "+spinnerFrame(p.spinner), color.FgYellow)...)
+		}
+		_, _ = p.writer.WriteCells(cells)
+	}
 
+	// the scrollbar is drawn on the column before the last one so that the
+	// terminal doesn't wrap the line
+	width, height, err := term.Size()
+	scrollbar := err == nil && width > 2 && total > p.list.Size()
+	rows := 2 // the search line and the message
+	var hints []term.Cell
+	if p.opts.Hints {
+		hints = genHints(p.hints())
+		if err == nil {
+			hints = fitCells(hints, width)
+		}
+		rows++
+	}
+	top, length := scrollThumb(p.list.Start(), p.list.Size(), total)
+	outputs := make([][][]term.Cell, len(items))
+	var widest int
 	for i := range items {
-		output := p.itemRenderer(items[i], p.list.Matches(items[i]), (i == idx))
-		for _, l := range output {
+		outputs[i] = p.itemRenderer(items[i], p.list.Matches(items[i]), (i == idx))
+		for _, l := range outputs[i] {
+			if w := cellsWidth(l); w > widest && !isHeader(items[i]) {
+				widest = w
+			}
+		}
+	}
+	// the items are scrolled no further than the end of the widest one
+	if err == nil {
+		visible := width
+		if scrollbar {
+			visible = width - 2
+		}
+		if p.hoffset > widest-visible {
+			p.hoffset = widest - visible
+		}
+		if p.hoffset < 0 {
+			p.hoffset = 0
+		}
+	}
+	prefix := runewidth.StringWidth(p.theme.CursorPrefix)
+	for i, output := range outputs {
+		for j, l := range output {
+			if !isHeader(items[i]) {
+				l = scrollItem(l, prefix, p.hoffset)
+			}
+			if scrollbar {
+				bar := term.Cell{Ch: '│', Attr: []color.Attribute{color.Faint}}
+				if j > 0 {
+					bar.Ch = ' '
+				} else if i >= top && i < top+length {
+					bar = term.Cell{Ch: '┃', Attr: []color.Attribute{color.FgCyan}}
+				}
+				l = append(fitCells(l, width-2), bar)
+			}
 			_, _ = p.writer.WriteCells(l)
 		}
+		rows += len(output)
 	}
 
-	_, _ = p.writer.WriteCells(nil) // add an empty line
+	_, _ = p.writer.WriteCells(p.message) // an empty line unless there is a message
 	if idx != NotFound {
-		for _, line := range p.informationRenderer(items[idx]) {
+		info := p.information(items[idx])
+		limit := len(info)
+		if p.infoFolded {
+			limit = foldedInfoLines
+		}
+		if err == nil && height-rows < limit {
+			limit = height - rows // the list is kept on the screen
+		}
+		var find func(string) [][]int
+		if query := strings.TrimSpace(p.input); len(query) > 0 {
+			find = occurrences(p.searchMode, query)
+		}
+		for _, line := range foldInfo(info, limit) {
+			if find != nil {
+				line = highlightOccurrences(line, find, p.theme.MatchAttr)
+			}
 			_, _ = p.writer.WriteCells(line)
 		}
+	} else if p.list.Loading() {
+		_, _ = p.writer.WriteCells(term.Cprint("Loading...", color.Faint))
 	} else {
-		_, _ = p.writer.WriteCells(term.Cprint("Not found.", color.FgRed))
+		_, _ = p.writer.WriteCells(p.emptyMessage)
+	}
+	if hints != nil {
+		_, _ = p.writer.WriteCells(hints)
+	}
+}
+
+// hints returns the descriptions and the keys of the primary key bindings,
+// followed by the keys of the help and quit
+func (p *Prompt) hints() [][2]string {
+	var hints [][2]string
+	item := p.selectedItem()
+	for _, kb := range p.keyBindings {
+		if kb.Primary && (item == nil || kb.enabled(item)) {
+			desc := kb.Desc
+			if kb.DescFunc != nil && item != nil {
+				desc = kb.DescFunc(item)
+			}
+			hints = append(hints, [2]string{desc, kb.Display})
+		}
+	}
+	for _, bk := range p.keymap {
+		switch bk.action {
+		case actionToggleHelp:
+			hints = append(hints, [2]string{"help", keyName(bk.key)})
+		case actionQuit:
+			hints = append(hints, [2]string{"quit", keyName(bk.key)})
+		}
+	}
+	return hints
+}
+
+// foldedInfoLines is the number of the summary lines of the folded information
+const foldedInfoLines = 3
+
+// foldInfo returns at most limit lines of the information, the last one tells
+// how many lines are left out
+func foldInfo(info [][]term.Cell, limit int) [][]term.Cell {
+	if len(info) <= limit {
+		return info
+	}
+	if limit < 1 {
+		limit = 1
+	}
+	more := fmt.Sprintf("… (%d more lines)", len(info)-limit+1)
+	return append(info[:limit-1:limit-1], term.Cprint(more, color.Faint))
+}
+
+// information returns the output of the information renderer for the item. It
+// is cached until the selection changes or the cache is invalidated.
+func (p *Prompt) information(item interface{}) [][]term.Cell {
+	if p.infoValid && p.infoItem == item {
+		return p.info
+	}
+	p.info = p.informationRenderer(item)
+	p.infoItem = item
+	p.infoValid = item != nil && reflect.TypeOf(item).Comparable()
+	return p.info
+}
+
+// SetMessage displays the cells below the list until the timeout passes or a
+// key is pressed, a zero timeout keeps it until a key is pressed. It gives the
+// feedback of a handler without quitting, and must be called from a handler or
+// a function passed to Post.
+func (p *Prompt) SetMessage(cells []term.Cell, timeout time.Duration) {
+	p.clearMessage()
+	p.message = cells
+	if timeout > 0 {
+		p.messageTimer = time.NewTimer(timeout)
 	}
 }
 
-// AddKeyBinding adds a key-function map to prompt
+func (p *Prompt) clearMessage() {
+	p.message = nil
+	if p.messageTimer != nil {
+		p.messageTimer.Stop()
+		p.messageTimer = nil
+	}
+}
+
+// messageC returns the channel of the message timer, nil if the message has no
+// timeout
+func (p *Prompt) messageC() <-chan time.Time {
+	if p.messageTimer == nil {
+		return nil
+	}
+	return p.messageTimer.C
+}
+
+// Copy sets the clipboard of the terminal to the text, e.g. to paste the hash of
+// the selected commit. It must be called from a handler or a function passed to
+// Post.
+func (p *Prompt) Copy(text string) error {
+	return p.writer.Copy(text)
+}
+
+// AddJob adds a function to be run in its own goroutine while the prompt runs,
+// the context is cancelled when Run returns. The job can update the prompt with
+// Post, e.g. to reload the items when they change.
+func (p *Prompt) AddJob(job func(ctx context.Context)) {
+	p.jobs = append(p.jobs, job)
+}
+
+// Post queues fn to be run by the main loop with the prompt locked like the
+// handlers, the prompt is rendered afterwards. It is safe to call from any
+// goroutine so that a background job can update the prompt when it progresses.
+func (p *Prompt) Post(fn func()) {
+	p.postMx.Lock()
+	p.posts = append(p.posts, fn)
+	p.postMx.Unlock()
+	p.notifyPosted()
+}
+
+// Feed queues the keys to be handled as if they were typed, e.g. to replay a
+// recorded macro. It can be called before Run or from any goroutine, the keys
+// are handled one at a time by the main loop in between the typed ones.
+func (p *Prompt) Feed(keys ...rune) {
+	p.postMx.Lock()
+	p.fed = append(p.fed, keys...)
+	p.postMx.Unlock()
+	p.notifyPosted()
+}
+
+// nextFed pops the next key queued by Feed, the main loop is notified again
+// if more keys are left
+func (p *Prompt) nextFed() (rune, bool) {
+	p.postMx.Lock()
+	defer p.postMx.Unlock()
+	if len(p.fed) == 0 {
+		return 0, false
+	}
+	key := p.fed[0]
+	p.fed = p.fed[1:]
+	if len(p.fed) > 0 {
+		p.notifyPosted()
+	}
+	return key, true
+}
+
+// notifyPosted wakes the main loop up to run the posts and the fed keys
+func (p *Prompt) notifyPosted() {
+	select {
+	case p.posted <- struct{}{}:
+	default: // the main loop is already notified
+	}
+}
+
+func (p *Prompt) runPosts() {
+	p.postMx.Lock()
+	posts := p.posts
+	p.posts = nil
+	p.postMx.Unlock()
+
+	p.mx.Lock()
+	defer p.mx.Unlock()
+	for _, fn := range posts {
+		fn()
+	}
+}
+
+// InvalidateInformation makes the information of the selected item to be
+// rendered again, it should be called if the data behind the item changes
+func (p *Prompt) InvalidateInformation() {
+	p.info = nil
+	p.infoItem = nil
+	p.infoValid = false
+}
+
+// AddKeyBinding adds a key-function map to prompt. It returns an error if the
+// key is used by the prompt itself or it is already bound.
 func (p *Prompt) AddKeyBinding(b *KeyBinding) error {
+	if p.reservedKey(b.Key) {
+		return fmt.Errorf("key %q is reserved by the prompt", b.Key)
+	}
+	for _, kb := range p.keyBindings {
+		if kb.Key == b.Key && kb.Then == b.Then {
+			return fmt.Errorf("key %q is already bound to %q", b.Display, kb.Desc)
+		}
+	}
 	p.keyBindings = append(p.keyBindings, b)
 	return nil
 }
 
+// reservedKey returns true if the key is handled before the key bindings
+func (p *Prompt) reservedKey(key rune) bool {
+	switch key {
+	case term.Enter, term.NewLine, term.PasteStart, rune(term.KeyCtrlC), rune(term.KeyCtrlD):
+		return true
+	}
+	return p.mapped(key)
+}
+
 // default key handling function
 func (p *Prompt) onKey(key rune) error {
+	p.clearMessage()
 	if p.helpMode {
 		p.helpMode = false
 		return nil
 	}
+	if p.inputMode && p.onInputKey(key) {
+		return nil
+	}
+
+	// the pending key either completes a chord or runs its own binding
+	if first := p.chordKey; first != 0 {
+		p.stopChord()
+		if a, ok := p.builtinChord(first, key); ok {
+			p.runAction(a)
+			return nil
+		}
+		if kb := p.chordBinding(first, key); kb != nil {
+			return p.runBinding(kb)
+		}
+		if err := p.runBinding(p.binding(first)); err != nil {
+			return err
+		}
+	}
+
+	if p.countKey(key) {
+		return nil
+	}
+	count := p.count
+	p.count = 0
+
+	if a, ok := p.builtin(key); ok {
+		if a == actionQuit {
+			return p.quitHandler(p.selectedItem())
+		}
+		if a.motion() {
+			for i := 1; i < count && i < p.list.Len(); i++ {
+				p.runAction(a)
+			}
+		}
+		p.runAction(a)
+		return nil
+	}
+	if p.inputMode {
+		return nil
+	}
+
+	if timeout, ok := p.startsChord(key); ok {
+		p.chordKey = key
+		p.chordTimer = time.NewTimer(timeout)
+		return nil
+	}
+	return p.runBinding(p.binding(key))
+}
+
+// countKey accumulates the digits of a numeric prefix with the vim keys, a
+// leading zero and the digits with a binding are not counted
+func (p *Prompt) countKey(key rune) bool {
+	if !p.opts.VimKeys || p.inputMode || key < '0' || key > '9' {
+		return false
+	}
+	if key == '0' && p.count == 0 {
+		return false
+	}
+	if _, ok := p.builtin(key); ok || p.bound(key) {
+		return false
+	}
+	p.count = p.count*10 + int(key-'0')
+	return true
+}
+
+// runBinding calls the handler of the binding with the selected item, nothing
+// is done if there is no binding or item
+func (p *Prompt) runBinding(kb *KeyBinding) error {
+	if kb == nil {
+		return nil
+	}
+	item := p.selectedItem()
+	if item == nil || !kb.enabled(item) {
+		return nil
+	}
+	return kb.Handler(item)
+}
+
+// enabled returns true if the binding applies to the item
+func (kb *KeyBinding) enabled(item interface{}) bool {
+	return kb.Enabled == nil || kb.Enabled(item)
+}
+
+// binding returns the single key binding of the key
+func (p *Prompt) binding(key rune) *KeyBinding {
+	for _, kb := range p.keyBindings {
+		if kb.Key == key && kb.Then == 0 {
+			return kb
+		}
+	}
+	return nil
+}
+
+// chordBinding returns the binding of the two keys
+func (p *Prompt) chordBinding(first, second rune) *KeyBinding {
+	for _, kb := range p.keyBindings {
+		if kb.Key == first && kb.Then != 0 && kb.Then == second {
+			return kb
+		}
+	}
+	return nil
+}
+
+// startsChord returns true and the time to wait for the second key if the key
+// is the first key of a chord
+func (p *Prompt) startsChord(key rune) (time.Duration, bool) {
+	for _, kb := range p.keyBindings {
+		if kb.Key == key && kb.Then != 0 {
+			if kb.Timeout > 0 {
+				return kb.Timeout, true
+			}
+			return chordTimeout, true
+		}
+	}
+	for _, bk := range p.keymap {
+		if bk.key == key && bk.then != 0 {
+			return chordTimeout, true
+		}
+	}
+	return 0, false
+}
+
+// flushChord runs the binding of the pending key since no key followed it
+func (p *Prompt) flushChord() error {
+	first := p.chordKey
+	p.stopChord()
+	return p.runBinding(p.binding(first))
+}
+
+func (p *Prompt) stopChord() {
+	p.chordKey = 0
+	if p.chordTimer != nil {
+		p.chordTimer.Stop()
+		p.chordTimer = nil
+	}
+}
+
+// chordC returns the channel of the chord timer, nil if no chord is pending
+func (p *Prompt) chordC() <-chan time.Time {
+	if p.chordTimer == nil {
+		return nil
+	}
+	return p.chordTimer.C
+}
 
+// selectedItem returns the item under the cursor or nil if the list is empty
+func (p *Prompt) selectedItem() interface{} {
+	items, idx := p.list.Items()
+	if idx == NotFound {
+		return nil
+	}
+	return items[idx]
+}
+
+// onInputKey edits the search input around the caret. It returns false if the
+// key is not an editing key so that it can be handled by onKey.
+func (p *Prompt) onInputKey(key rune) bool {
+	input := []rune(p.input)
+	before, after := input[:p.caret], input[p.caret:]
 	switch key {
 	case term.ArrowUp:
-		p.list.Prev()
+		// recall the history only if the cursor can't move up any further
+		if p.history == nil || p.list.Cursor() > 0 {
+			return false
+		}
+		if entry, ok := p.history.prev(p.input); ok {
+			p.setInput(entry)
+		}
+		return true
 	case term.ArrowDown:
-		p.list.Next()
+		if p.history == nil {
+			return false
+		}
+		entry, ok := p.history.next()
+		if ok {
+			p.setInput(entry)
+		}
+		return ok
 	case term.ArrowLeft:
-		p.list.PageDown()
+		if p.caret > 0 {
+			p.caret--
+		}
+		return true
 	case term.ArrowRight:
-		p.list.PageUp()
+		if p.caret < len(input) {
+			p.caret++
+		}
+		return true
+	case rune(term.KeyCtrlA), term.Home:
+		p.caret = 0
+		return true
+	case rune(term.KeyCtrlE), term.End:
+		p.caret = len(input)
+		return true
+	case term.Backspace, term.Backspace2:
+		if len(before) == 0 {
+			return true
+		}
+		before = before[:len(before)-1]
+	case term.Delete:
+		if len(after) == 0 {
+			return true
+		}
+		after = after[1:]
+	case rune(term.KeyCtrlU):
+		before = nil
+	case rune(term.KeyCtrlW):
+		before = []rune(deleteWord(string(before)))
 	default:
+		if a, ok := p.builtin(key); (ok && a == actionToggleSearch) || !unicode.IsPrint(key) {
+			return false
+		}
+		before = append(before, key)
+	}
+	p.input = string(before) + string(after)
+	p.caret = len(before)
+	p.search()
+	return true
+}
 
-		if key == '/' {
-			p.inputMode = !p.inputMode
-		} else if p.inputMode {
-			switch key {
-			case term.Backspace, term.Backspace2:
-				if len(p.input) > 0 {
-					_, size := utf8.DecodeLastRuneInString(p.input)
-					p.input = p.input[0 : len(p.input)-size]
-				}
-			case rune(term.KeyCtrlU):
-				p.input = ""
-			default:
-				p.input += string(key)
+// Input reads a line from the user in place of the search line, it is meant to
+// be called from a key binding handler. An empty string is returned if the
+// input is cancelled with esc or ctrl-c.
+func (p *Prompt) Input(label, init string) (string, error) {
+	input := []rune(init)
+	defer func() {
+		p.inputLine = nil
+	}()
+	for {
+		p.inputLine = append(term.Cprint(label+": ", color.Faint), term.Cprint(string(input), color.FgWhite)...)
+		p.inputLine = append(p.inputLine, cursorCell(p.opts.BlinkCursor))
+		p.render()
+
+		r, _, err := p.reader.ReadRune()
+		if err != nil {
+			return "", err
+		}
+		switch r {
+		case term.PasteStart:
+			paste, err := p.reader.ReadPaste()
+			if err != nil {
+				return "", err
+			}
+			input = append(input, []rune(printable(paste))...)
+		case term.Enter, term.NewLine:
+			return strings.TrimSpace(string(input)), nil
+		case rune(term.KeyESC), rune(term.KeyCtrlC), rune(term.KeyCtrlD):
+			return "", nil
+		case term.Backspace, term.Backspace2:
+			if len(input) > 0 {
+				input = input[:len(input)-1]
 			}
-			p.list.Search(p.input)
-		} else if key == '?' {
-			p.helpMode = !p.helpMode
-		} else if p.opts.VimKeys && key == 'k' {
-			// refactor vim keys
-			p.list.Prev()
-		} else if p.opts.VimKeys && key == 'j' {
-			p.list.Next()
-		} else if p.opts.VimKeys && key == 'h' {
-			p.list.PageDown()
-		} else if p.opts.VimKeys && key == 'l' {
-			p.list.PageUp()
-		} else {
-			items, idx := p.list.Items()
-			if idx == NotFound {
-				return nil
+		case rune(term.KeyCtrlU):
+			input = nil
+		case rune(term.KeyCtrlW):
+			input = []rune(deleteWord(string(input)))
+		default:
+			if unicode.IsPrint(r) {
+				input = append(input, r)
 			}
+		}
+	}
+}
 
-			for _, kb := range p.keyBindings {
-				if kb.Key == key {
-					return kb.Handler(items[idx])
-				}
+// Confirm asks a yes/no question in place of the search line, it is meant to be
+// called from a key binding handler before a destructive action. The answer
+// is no unless y is pressed.
+func (p *Prompt) Confirm(message string) (bool, error) {
+	defer func() {
+		p.inputLine = nil
+	}()
+	p.inputLine = append(term.Cprint(message+" ", color.FgYellow), term.Cprint("[y/N]", color.Faint)...)
+	for {
+		p.render()
+
+		r, _, err := p.reader.ReadRune()
+		if err != nil {
+			return false, err
+		}
+		switch r {
+		case 'y', 'Y':
+			return true, nil
+		case 'n', 'N', term.Enter, term.NewLine, rune(term.KeyESC), rune(term.KeyCtrlC), rune(term.KeyCtrlD):
+			return false, nil
+		}
+	}
+}
+
+// insertInput adds the text to the search input at the caret with a single
+// search, the characters that can't be typed are dropped
+func (p *Prompt) insertInput(text string) {
+	input := []rune(p.input)
+	paste := []rune(printable(text))
+	if len(paste) == 0 {
+		return
+	}
+	before := append(input[:p.caret:p.caret], paste...)
+	p.input = string(before) + string(input[p.caret:])
+	p.caret = len(before)
+	p.search()
+}
+
+// printable replaces the line breaks and tabs of the text with spaces and drops
+// the other control characters
+func printable(text string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '\n' || r == '\r' || r == '\t':
+			return ' '
+		case !unicode.IsPrint(r):
+			return -1
+		}
+		return r
+	}, text)
+}
+
+// cancelSearch clears the search input and leaves the input mode
+func (p *Prompt) cancelSearch() {
+	p.searchPending = false
+	p.input = ""
+	p.caret = 0
+	p.inputMode = false
+	p.list.CancelSearch()
+}
+
+// setInput replaces the search input and moves the caret to its end
+func (p *Prompt) setInput(input string) {
+	p.input = input
+	p.caret = utf8.RuneCountInString(input)
+	p.search()
+}
+
+// search filters the list with the current input. If there is a search delay
+// the search is postponed until no key is pressed for that long.
+func (p *Prompt) search() {
+	delay := p.opts.SearchDelay
+	if delay <= 0 {
+		p.list.Search(p.input)
+		return
+	}
+	if p.searchTimer == nil {
+		p.searchTimer = time.NewTimer(delay)
+	} else {
+		if !p.searchTimer.Stop() {
+			select {
+			case <-p.searchTimer.C:
+			default:
 			}
 		}
+		p.searchTimer.Reset(delay)
 	}
+	p.searchPending = true
+}
 
-	return nil
+// flushSearch runs the postponed search immediately
+func (p *Prompt) flushSearch() {
+	if !p.searchPending {
+		return
+	}
+	p.searchPending = false
+	p.list.Search(p.input)
 }
 
-func (p *Prompt) allControls() map[string]string {
-	controls := make(map[string]string)
-	controls["← ↓ ↑ → (h,j,k,l)"] = "navigation"
-	controls["/"] = "toggle search"
+// searchC returns the channel of the debounce timer
+func (p *Prompt) searchC() <-chan time.Time {
+	if p.searchTimer == nil {
+		return nil
+	}
+	return p.searchTimer.C
+}
+
+// commitSearch records the current search term to the history
+func (p *Prompt) commitSearch() {
+	if p.history != nil {
+		_ = p.history.add(p.input)
+	}
+}
+
+// deleteWord removes the last whitespace delimited word of the input with the
+// spaces following it, just like the readline's ctrl-w
+func deleteWord(input string) string {
+	input = strings.TrimRightFunc(input, unicode.IsSpace)
+	i := strings.LastIndexFunc(input, unicode.IsSpace)
+	if i < 0 {
+		return ""
+	}
+	_, size := utf8.DecodeRuneInString(input[i:])
+	return input[:i+size]
+}
+
+func (p *Prompt) allControls() []control {
+	controls := p.builtinControls()
+	item := p.selectedItem()
 	for _, kb := range p.keyBindings {
-		controls[kb.Display] = kb.Desc
+		controls = append(controls, control{
+			keys:     kb.Display,
+			desc:     kb.Desc,
+			category: kb.Category,
+			disabled: item != nil && !kb.enabled(item),
+		})
+	}
+	if p.opts.SortHelp {
+		sort.SliceStable(controls, func(i, j int) bool { return controls[i].desc < controls[j].desc })
 	}
 	return controls
 }
@@ -343,6 +1476,7 @@ func (p *Prompt) allControls() map[string]string {
 // State return the current replace-able vars as a struct
 func (p *Prompt) State() *State {
 	scroll := p.list.Start()
+	_, matched := listPosition(p.list)
 	return &State{
 		List:        p.list,
 		SearchMode:  p.inputMode,
@@ -351,22 +1485,72 @@ func (p *Prompt) State() *State {
 		Cursor:      p.list.Cursor(),
 		Scroll:      scroll,
 		ListSize:    p.list.Size(),
+		Matched:     matched,
+		Total:       listTotal(p.list),
+		Selected:    p.selectedItem(),
 	}
 }
 
+// listPosition returns the position of the selected item and the number of
+// the matched items without the headers if the list implementation can tell
+// them, otherwise the cursor position and the length of the list
+func listPosition(l List) (int, int) {
+	if p, ok := l.(interface{ Position() (int, int) }); ok {
+		return p.Position()
+	}
+	return l.Cursor() + 1, l.Len()
+}
+
+// listTotal returns the number of all of the items if the list implementation
+// can tell it, otherwise the number of the matched ones
+func listTotal(l List) int {
+	if t, ok := l.(interface{ Total() int }); ok {
+		return t.Total()
+	}
+	return l.Len()
+}
+
 // SetState replaces the state of the prompt
 func (p *Prompt) SetState(state *State) {
 	p.list = state.List
+	p.configureList()
+	if l, ok := p.list.(interface{ Filter(func(interface{}) bool) }); ok && p.filter != nil {
+		l.Filter(p.filter)
+	}
+	p.InvalidateInformation()
 	p.inputMode = state.SearchMode
 	p.input = state.SearchStr
+	p.caret = utf8.RuneCountInString(p.input)
 	p.itemsLabel = state.SearchLabel
 	p.list.SetCursor(state.Cursor)
 	p.list.SetStart(state.Scroll)
+	if state.Selected == nil {
+		return
+	}
+	if l, ok := p.list.(interface {
+		Find(func(interface{}) bool) int
+	}); ok {
+		if i := l.Find(func(item interface{}) bool {
+			if p.equal != nil {
+				return p.equal(item, state.Selected)
+			}
+			return sameItem(item, state.Selected)
+		}); i != NotFound {
+			p.list.SetCursor(i)
+		}
+	}
+}
+
+// Frame returns the last rendered frame, the escape sequences are stripped
+// unless ansi is set. It is meant for the tests driving the prompt with WithIO,
+// and should be called after Run returns or from a handler.
+func (p *Prompt) Frame(ansi bool) string {
+	return p.writer.Frame(ansi)
 }
 
-// ListSize returns the size of the items that is renderer each time
+// ListSize returns the number of the items that is rendered each time
 func (p *Prompt) ListSize() int {
-	return p.opts.LineSize
+	return p.list.Size()
 }
 
 // SetExitMsg adds a rendered cell grid to be printed after prompt is finished
@@ -0,0 +1,660 @@
+package prompt
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/isacikgoz/gitin/term"
+)
+
+func TestAddKeyBinding(t *testing.T) {
+	list, err := NewList([]string{"a", "b"}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := func(interface{}) error { return nil }
+	var tests = []struct {
+		vimKeys bool
+		keys    []rune
+		fail    bool
+	}{
+		{true, []rune{'c', 'd'}, false},
+		{true, []rune{'c', 'c'}, true},
+		{true, []rune{'/'}, true},
+		{true, []rune{'j'}, true},
+		{false, []rune{'j'}, false},
+		{false, []rune{'q'}, true},
+	}
+	for _, test := range tests {
+		p := Create("Items", &Options{VimKeys: test.vimKeys}, list)
+		var err error
+		for _, key := range test.keys {
+			if err = p.AddKeyBinding(&KeyBinding{Key: key, Handler: handler}); err != nil {
+				break
+			}
+		}
+		if (err != nil) != test.fail {
+			t.Errorf("input: %q vim keys: %t\n error: %v", test.keys, test.vimKeys, err)
+		}
+	}
+}
+
+func TestRemapKey(t *testing.T) {
+	list, err := NewList([]string{"a", "b"}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := func(interface{}) error { return nil }
+	p := Create("Items", &Options{VimKeys: true}, list)
+
+	p.UnbindKey('j')
+	if err := p.AddKeyBinding(&KeyBinding{Key: 'j', Handler: handler}); err != nil {
+		t.Errorf("unbound key should be available: %v", err)
+	}
+	if err := p.RemapKey('/', 'j'); err == nil {
+		t.Errorf("remapping to a bound key should fail")
+	}
+	if err := p.RemapKey('/', 's'); err != nil {
+		t.Errorf("remapping to a free key should succeed: %v", err)
+	}
+	if err := p.RemapKey('/', 'x'); err == nil {
+		t.Errorf("remapping a key without a built-in behavior should fail")
+	}
+	if err := p.AddKeyBinding(&KeyBinding{Key: '/', Handler: handler}); err != nil {
+		t.Errorf("remapped key should be available: %v", err)
+	}
+	if err := p.AddKeyBinding(&KeyBinding{Key: 's', Handler: handler}); err == nil {
+		t.Errorf("the new key of a built-in should be reserved")
+	}
+}
+
+func TestResize(t *testing.T) {
+	items := make([]int, 20)
+	list, err := NewList(items, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list.SetCursor(15)
+
+	var tests = []struct {
+		height, max int
+		size        int
+	}{
+		{8, 0, 4},
+		{3, 0, 1},
+		{50, 0, 32},
+		{50, 5, 5},
+		{17, 0, 10},
+	}
+	for _, test := range tests {
+		size := lineSizeFor(test.height, test.max)
+		if size != test.size {
+			t.Errorf("height: %d max: %d\n got size %d, want %d", test.height, test.max, size, test.size)
+		}
+		list.SetSize(size)
+		start, cursor := list.Start(), list.Cursor()
+		if cursor != 15 {
+			t.Errorf("height: %d resize moved the cursor to %d", test.height, cursor)
+		}
+		if cursor < start || cursor >= start+size {
+			t.Errorf("height: %d cursor %d is out of the viewport %d-%d", test.height, cursor, start, start+size)
+		}
+		if start < 0 || (start+size > list.Len() && start > 0) {
+			t.Errorf("height: %d viewport %d-%d exceeds the %d items", test.height, start, start+size, list.Len())
+		}
+	}
+}
+
+func TestFoldInfo(t *testing.T) {
+	info := [][]term.Cell{term.Cprint("a"), term.Cprint("b"), term.Cprint("c"), term.Cprint("d")}
+	var tests = []struct {
+		limit int
+		want  string
+	}{
+		{5, "a b c d"},
+		{4, "a b c d"},
+		{3, "a b … (2 more lines)"},
+		{1, "… (4 more lines)"},
+		{0, "… (4 more lines)"},
+	}
+	for _, test := range tests {
+		var lines []string
+		for _, line := range foldInfo(info, test.limit) {
+			var s strings.Builder
+			for _, c := range line {
+				s.WriteRune(c.Ch)
+			}
+			lines = append(lines, s.String())
+		}
+		if got := strings.Join(lines, " "); got != test.want {
+			t.Errorf("limit: %d\n got %q, want %q", test.limit, got, test.want)
+		}
+	}
+}
+
+func TestRunScriptedKeys(t *testing.T) {
+	var tests = []struct {
+		keys     string
+		selected interface{}
+		err      error
+	}{
+		{"j\r", "b", nil},
+		{"jjk\r", "b", nil},
+		{"2j\r", "c", nil},
+		{"9jk\r", "b", nil},
+		{"2x1j\r", "b", nil},
+		{"/c\r", "c", nil},
+		{"jq", nil, nil},
+		{"j\x03", nil, ErrAborted},
+	}
+	for _, test := range tests {
+		list, err := NewList([]string{"a", "b", "c"}, 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out bytes.Buffer
+		var selected interface{}
+		var p *Prompt
+		p = Create("Items", &Options{VimKeys: true, DisableColor: true}, list,
+			WithIO(strings.NewReader(test.keys), &out),
+			WithSelectionHandler(func(item interface{}) error {
+				selected = item
+				p.Stop()
+				return nil
+			}),
+		)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		err = p.Run(ctx)
+		cancel()
+		if err != test.err {
+			t.Errorf("keys: %q\n got error %v, want %v", test.keys, err, test.err)
+		}
+		if selected != test.selected {
+			t.Errorf("keys: %q\n got selection %v, want %v", test.keys, selected, test.selected)
+		}
+		if !strings.Contains(out.String(), "Items") {
+			t.Errorf("keys: %q\n the label is not rendered: %q", test.keys, out.String())
+		}
+	}
+}
+
+func TestChordKeyBinding(t *testing.T) {
+	var tests = []struct {
+		keys string
+		want string
+	}{
+		{"dd", "chord"},
+		{"d", "single"},
+		{"dx", "single"},
+		{"jdd", "chord"},
+	}
+	for _, test := range tests {
+		list, err := NewList([]string{"a", "b", "c"}, 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got string
+		p := Create("Items", &Options{VimKeys: true, DisableColor: true}, list,
+			WithIO(strings.NewReader(test.keys), &bytes.Buffer{}),
+		)
+		handler := func(name string) func(interface{}) error {
+			return func(interface{}) error {
+				got = name
+				p.Stop()
+				return nil
+			}
+		}
+		bindings := []*KeyBinding{
+			{Key: 'd', Then: 'd', Timeout: 50 * time.Millisecond, Display: "dd", Handler: handler("chord")},
+			{Key: 'd', Display: "d", Handler: handler("single")},
+		}
+		for _, kb := range bindings {
+			if err := p.AddKeyBinding(kb); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		err = p.Run(ctx)
+		cancel()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.want {
+			t.Errorf("keys: %q\n got %q, want %q", test.keys, got, test.want)
+		}
+	}
+}
+
+func TestChordBeforeEnter(t *testing.T) {
+	list, err := NewList([]string{"a", "b", "c"}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	var p *Prompt
+	p = Create("Items", &Options{VimKeys: true, DisableColor: true}, list,
+		WithIO(strings.NewReader("d\r"), &bytes.Buffer{}),
+		WithSelectionHandler(func(interface{}) error {
+			got = append(got, "enter")
+			p.Stop()
+			return nil
+		}),
+	)
+	bindings := []*KeyBinding{
+		{Key: 'd', Then: 'd', Timeout: time.Second, Display: "dd", Handler: func(interface{}) error { return nil }},
+		{Key: 'd', Display: "d", Handler: func(interface{}) error {
+			got = append(got, "single")
+			return nil
+		}},
+	}
+	for _, kb := range bindings {
+		if err := p.AddKeyBinding(kb); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := p.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if want := "single enter"; strings.Join(got, " ") != want {
+		t.Errorf("got %q, want %q", strings.Join(got, " "), want)
+	}
+}
+
+func TestSetMessage(t *testing.T) {
+	list, err := NewList([]string{"a", "b"}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var p *Prompt
+	p = Create("Items", &Options{DisableColor: true}, list,
+		WithIO(strings.NewReader("mx"), &bytes.Buffer{}),
+	)
+	err = p.AddKeyBinding(&KeyBinding{Key: 'm', Display: "m", Handler: func(item interface{}) error {
+		p.SetMessage(term.Cprint("hello"), 0)
+		return nil
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = p.AddKeyBinding(&KeyBinding{Key: 'x', Display: "x", Handler: func(item interface{}) error {
+		if !strings.Contains(p.Frame(false), "\nhello") {
+			t.Errorf("message is not rendered: %q", p.Frame(false))
+		}
+		p.Stop()
+		return nil
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(p.Frame(false), "hello") {
+		t.Errorf("message is not cleared by the key: %q", p.Frame(false))
+	}
+}
+
+func TestJobPanic(t *testing.T) {
+	list, err := NewList([]string{"a", "b"}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := Create("Items", &Options{}, list, WithIO(strings.NewReader(""), &bytes.Buffer{}))
+	p.AddJob(func(ctx context.Context) { panic("boom") })
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	defer func() {
+		gp, ok := recover().(*goroutinePanic)
+		if !ok || gp.value != "boom" {
+			t.Errorf("the panic of the job is not raised by Run, got %v", gp)
+		}
+	}()
+	p.Run(ctx)
+}
+
+func TestStopReleasesReader(t *testing.T) {
+	list, err := NewList([]string{"a", "b"}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	// more keys than the events can buffer, the reader is blocked on sending
+	// them when the prompt stops
+	w.Write(bytes.Repeat([]byte("x"), 100))
+	p := Create("Items", &Options{}, list, WithIO(r, &bytes.Buffer{}))
+	var once sync.Once
+	p.AddKeyBinding(&KeyBinding{Key: 'x', Handler: func(interface{}) error {
+		once.Do(p.Stop)
+		return nil
+	}})
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	w.Close() // wakes the reader if it is waiting for the input
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		buf := make([]byte, 1<<20)
+		stacks := string(buf[:runtime.Stack(buf, true)])
+		if !strings.Contains(stacks, "spawnEvents") {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("the reader is left running:\n%s", stacks)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// watchWriter closes seen once the text is written
+type watchWriter struct {
+	text string
+	seen chan struct{}
+	once sync.Once
+}
+
+func (w *watchWriter) Write(b []byte) (int, error) {
+	if strings.Contains(string(b), w.text) {
+		w.once.Do(func() { close(w.seen) })
+	}
+	return len(b), nil
+}
+
+func TestRenderStreamedItems(t *testing.T) {
+	items := make(chan interface{})
+	defer close(items)
+	list, err := NewAsyncList(items, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	out := &watchWriter{text: "streamed", seen: make(chan struct{})}
+	p := Create("Items", &Options{DisableColor: true}, list, WithIO(r, out))
+	done := make(chan error)
+	go func() { done <- p.Run(context.Background()) }()
+
+	// no key is pressed, the items are rendered as they are received
+	items <- "streamed"
+	select {
+	case <-out.seen:
+	case <-time.After(time.Second):
+		t.Error("the streamed item is not rendered")
+	}
+	p.Stop()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFeed(t *testing.T) {
+	list, err := NewList([]string{"a", "b", "c"}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := Create("Items", &Options{VimKeys: true}, list, WithIO(strings.NewReader(""), &bytes.Buffer{}))
+	var selected interface{}
+	p.AddKeyBinding(&KeyBinding{Key: 'x', Handler: func(item interface{}) error {
+		selected = item
+		p.Stop()
+		return nil
+	}})
+	p.Feed('j', 'j')
+	p.Feed('x')
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := p.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if selected != "c" {
+		t.Errorf("got %v selected by the fed keys, want c", selected)
+	}
+}
+
+func TestStateCounts(t *testing.T) {
+	list, err := NewList([]string{"foo", "bar", "baz"}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := Create("Items", &Options{}, list)
+	if s := p.State(); s.Matched != 3 || s.Total != 3 {
+		t.Errorf("got %d/%d items, want 3/3", s.Matched, s.Total)
+	}
+	list.Search("ba")
+	if s := p.State(); s.Matched != 2 || s.Total != 3 {
+		t.Errorf("got %d/%d items while searching, want 2/3", s.Matched, s.Total)
+	}
+}
+
+func TestCounterSkipsHeaders(t *testing.T) {
+	items := []interface{}{testHeader("A"), "a1", "a2", testHeader("B"), "b1"}
+	list, err := NewList(items, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	p := Create("Items", &Options{VimKeys: true, DisableColor: true}, list, WithIO(strings.NewReader("jjq"), &out))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := p.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if frame := p.Frame(false); !strings.Contains(frame, "Items 3/3") {
+		t.Errorf("the counter should skip the headers, got frame:\n%s", frame)
+	}
+	list.Search("a")
+	if s := p.State(); s.Matched != 2 || s.Total != 3 {
+		t.Errorf("got %d/%d items while searching, want 2/3", s.Matched, s.Total)
+	}
+}
+
+func TestEmptyMessage(t *testing.T) {
+	list, err := NewList([]string{"a", "b"}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	p := Create("Items", &Options{DisableColor: true}, list,
+		WithIO(strings.NewReader("/zz\x03"), &out),
+		WithEmptyMessage(term.Cprint("Nothing here")),
+	)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := p.Run(ctx); err != ErrAborted {
+		t.Fatalf("got %v, want ErrAborted", err)
+	}
+	if !strings.Contains(out.String(), "Nothing here") || strings.Contains(out.String(), "Not found.") {
+		t.Errorf("the empty message is not rendered: %q", out.String())
+	}
+}
+
+func TestBindingEnabled(t *testing.T) {
+	list, err := NewList([]string{"a", "b"}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var handled []interface{}
+	p := Create("Items", &Options{VimKeys: true}, list, WithIO(strings.NewReader("xjxq"), &bytes.Buffer{}))
+	p.AddKeyBinding(&KeyBinding{
+		Key:     'x',
+		Enabled: func(item interface{}) bool { return item != "a" },
+		Handler: func(item interface{}) error {
+			handled = append(handled, item)
+			return nil
+		},
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := p.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(handled) != 1 || handled[0] != "b" {
+		t.Errorf("got %v handled, want only b", handled)
+	}
+}
+
+type testFile struct{ path string }
+
+func (f *testFile) Identity() string { return f.path }
+
+func TestSetStateKeepsSelection(t *testing.T) {
+	var tests = []struct {
+		reloaded []string
+		want     int
+	}{
+		{[]string{"a", "b", "c"}, 1},
+		{[]string{"x", "a", "b", "c"}, 2},
+		{[]string{"b", "c"}, 0},
+		{[]string{"a", "c"}, 1},
+		{[]string{"a"}, 0},
+	}
+	for _, test := range tests {
+		list, err := NewList([]*testFile{{"a"}, {"b"}, {"c"}}, 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := Create("Files", &Options{}, list)
+		p.list.SetCursor(1)
+
+		var files []*testFile
+		for _, path := range test.reloaded {
+			files = append(files, &testFile{path})
+		}
+		state := p.State()
+		if state.List, err = NewList(files, 5); err != nil {
+			t.Fatal(err)
+		}
+		p.SetState(state)
+		if got := p.list.Cursor(); got != test.want {
+			t.Errorf("reloaded %v: cursor is %d, want %d", test.reloaded, got, test.want)
+		}
+	}
+}
+
+func TestWithEqual(t *testing.T) {
+	type file struct {
+		path  string
+		lines []int // not comparable
+	}
+	list, err := NewList([]file{{"a", nil}, {"b", nil}, {"c", nil}}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := Create("Files", &Options{}, list, WithEqual(func(a, b interface{}) bool {
+		return a.(file).path == b.(file).path
+	}))
+	p.list.SetCursor(1)
+
+	state := p.State()
+	if state.List, err = NewList([]file{{"x", []int{1}}, {"a", []int{2}}, {"b", []int{3}}}, 5); err != nil {
+		t.Fatal(err)
+	}
+	p.SetState(state)
+	if got := p.list.Cursor(); got != 2 {
+		t.Errorf("cursor is %d after the reload, want 2", got)
+	}
+	if got := p.list.Index(); got != 2 {
+		t.Errorf("index is %d, want 2", got)
+	}
+}
+
+func TestFrame(t *testing.T) {
+	list, err := NewList([]string{"a", "b", "c"}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := Create("Items", &Options{VimKeys: true, DisableColor: true}, list,
+		WithIO(strings.NewReader("jq"), &bytes.Buffer{}),
+	)
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := "Items 2/3\n  a\n> b\n  c\n"
+	if got := p.Frame(false); got != want {
+		t.Errorf("got frame %q, want %q", got, want)
+	}
+}
+
+func TestDefaultOptions(t *testing.T) {
+	opts := DefaultOptions()
+	want := Options{
+		VimKeys:     true,
+		HistorySize: 100,
+		SearchDelay: 80 * time.Millisecond,
+		BlinkCursor: true,
+		QuitKey:     'q',
+		HelpKey:     '?',
+	}
+	if *opts != want {
+		t.Errorf("got %+v\nwant %+v", *opts, want)
+	}
+
+	list, err := NewList([]string{"a", "b"}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := Create("Items", nil, list); !p.opts.VimKeys {
+		t.Errorf("prompt without options should use the defaults")
+	}
+}
+
+func TestPrint(t *testing.T) {
+	list, err := NewList([]interface{}{testHeader("Header"), "a", "b", "c"}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := Create("Items", &Options{DisableColor: true}, list)
+	var out bytes.Buffer
+	if err := p.Print(context.Background(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if want := "Header\na\nb\nc\n"; out.String() != want {
+		t.Errorf("got %q, want %q", out.String(), want)
+	}
+}
+
+func TestTopChord(t *testing.T) {
+	var tests = []struct {
+		keys   string
+		cursor int
+	}{
+		{"jjjggq", 0},
+		{"jjjgq", 3},
+		{"jjjgjq", 4},
+		{"jjjgggq", 0},
+	}
+	for _, test := range tests {
+		list, err := NewList([]string{"a", "b", "c", "d", "e"}, 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := Create("Items", &Options{VimKeys: true}, list, WithIO(strings.NewReader(test.keys), &bytes.Buffer{}))
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		err = p.Run(ctx)
+		cancel()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := p.State().Cursor; got != test.cursor {
+			t.Errorf("keys: %q\n got cursor %d, want %d", test.keys, got, test.cursor)
+		}
+	}
+}
@@ -3,71 +3,153 @@ package prompt
 import (
 	"fmt"
 	"sort"
+	"strings"
 
 	"github.com/fatih/color"
 	"github.com/isacikgoz/gitin/term"
+	"github.com/mattn/go-runewidth"
 )
 
-func itemText(item interface{}, matches []int, selected bool) [][]term.Cell {
+// Theme holds the attributes that are used by the default item renderer
+type Theme struct {
+	CursorPrefix string
+	CursorAttr   []color.Attribute
+	MatchAttr    []color.Attribute
+	HeaderAttr   []color.Attribute // the items implementing Header
+}
+
+// DefaultTheme returns the theme that the prompt uses unless WithTheme is given
+func DefaultTheme() *Theme {
+	return &Theme{
+		CursorPrefix: "> ",
+		CursorAttr:   []color.Attribute{color.FgCyan},
+		MatchAttr:    []color.Attribute{color.Underline},
+		HeaderAttr:   []color.Attribute{color.Bold},
+	}
+}
+
+func (t *Theme) itemText(item interface{}, matches []int, selected bool) [][]term.Cell {
 	var line []term.Cell
 	text := fmt.Sprint(item)
+	if isHeader(item) {
+		return [][]term.Cell{term.Cprint(text, t.HeaderAttr...)}
+	}
 	if selected {
-		line = append(line, term.Cprint("> ", color.FgCyan)...)
+		line = append(line, term.Cprint(t.CursorPrefix, t.CursorAttr...)...)
 	} else {
-		line = append(line, term.Cprint("  ", color.FgWhite)...)
+		blank := strings.Repeat(" ", runewidth.StringWidth(t.CursorPrefix))
+		line = append(line, term.Cprint(blank, color.FgWhite)...)
 	}
-	if len(matches) == 0 {
-		return [][]term.Cell{append(line, term.Cprint(text)...)}
+	return [][]term.Cell{append(line, term.HighlightAttrs(text, matches, nil, t.MatchAttr)...)}
+}
+
+// highlightOccurrences adds the attributes to the cells of the line that are
+// in the ranges found by find, the line is copied if anything is found
+func highlightOccurrences(line []term.Cell, find func(string) [][]int, attrs []color.Attribute) []term.Cell {
+	var text strings.Builder
+	offsets := make([]int, len(line))
+	for i, c := range line {
+		offsets[i] = text.Len()
+		text.WriteRune(c.Ch)
 	}
-	highlighted := make([]term.Cell, 0)
-	for _, r := range text {
-		highlighted = append(highlighted, term.Cell{
-			Ch: r,
-		})
+	locs := find(text.String())
+	if len(locs) == 0 {
+		return line
 	}
-	for _, m := range matches {
-		if m > len(highlighted)-1 {
-			continue
-		}
-		highlighted[m] = term.Cell{
-			Ch:   highlighted[m].Ch,
-			Attr: append(highlighted[m].Attr, color.Underline),
+	highlighted := make([]term.Cell, len(line))
+	copy(highlighted, line)
+	for i, c := range highlighted {
+		for _, loc := range locs {
+			if offsets[i] >= loc[0] && offsets[i] < loc[1] {
+				highlighted[i].Attr = append(c.Attr[:len(c.Attr):len(c.Attr)], attrs...)
+				break
+			}
 		}
 	}
-	line = append(line, highlighted...)
-	return [][]term.Cell{line}
+	return highlighted
 }
 
 // returns multiline so the return value will be a 2-d slice
-func genHelp(pairs map[string]string) [][]term.Cell {
+func genHelp(controls []control) [][]term.Cell {
 	var grid [][]term.Cell
-	n := map[string][]string{}
-	// sort keys alphabetically, sort by values
-	keys := make([]string, 0, len(pairs))
-	for k, v := range pairs {
-		n[v] = append(n[v], k)
-	}
-	for k := range n {
-		keys = append(keys, k)
+	// the keys sharing a description are listed in the line of its first one,
+	// the sections are in the order of their first control
+	var categories []string
+	descs := map[string][]string{}
+	keys := map[string][]string{}
+	disabled := map[string]bool{} // all of the keys of the description are disabled
+	for _, c := range controls {
+		category := c.category
+		if len(category) == 0 {
+			category = generalCategory
+		}
+		if _, ok := descs[category]; !ok {
+			categories = append(categories, category)
+		}
+		if _, ok := keys[c.desc]; !ok {
+			descs[category] = append(descs[category], c.desc)
+			disabled[c.desc] = true
+		}
+		keys[c.desc] = append(keys[c.desc], c.keys)
+		disabled[c.desc] = disabled[c.desc] && c.disabled
 	}
-	sort.Strings(keys)
-	for _, key := range keys {
-		grid = append(grid, append(term.Cprint(fmt.Sprintf("%s: ", key), color.Faint),
-			term.Cprint(n[key][0], color.FgYellow)...))
+	sort.SliceStable(categories, func(i, j int) bool {
+		return categories[i] != generalCategory && categories[j] == generalCategory
+	})
+	for i, category := range categories {
+		if i > 0 {
+			grid = append(grid, term.Cprint("", 0))
+		}
+		grid = append(grid, term.Cprint(category, color.Bold))
+		for _, desc := range descs[category] {
+			attr := color.FgYellow
+			if disabled[desc] {
+				attr = color.Faint
+			}
+			grid = append(grid, append(term.Cprint(fmt.Sprintf("  %s: ", desc), color.Faint),
+				term.Cprint(strings.Join(keys[desc], " "), attr)...))
+		}
 	}
 	grid = append(grid, term.Cprint("", 0))
 	grid = append(grid, term.Cprint("press any key to return.", color.Faint))
 	return grid
 }
 
-func renderSearch(placeholder string, inputMode bool, input string) []term.Cell {
+// genHints renders the pairs of descriptions and keys in a single line, like
+// "commit (c)  quit (q)"
+func genHints(hints [][2]string) []term.Cell {
+	var cells []term.Cell
+	for i, hint := range hints {
+		if i > 0 {
+			cells = append(cells, term.Cprint("  ", color.Faint)...)
+		}
+		cells = append(cells, term.Cprint(hint[0]+" ", color.Faint)...)
+		cells = append(cells, term.Cprint("("+hint[1]+")", color.FgYellow)...)
+	}
+	return cells
+}
+
+func renderSearch(placeholder string, mode SearchMode, inputMode bool, input string, caret int, blink bool) []term.Cell {
 	var cells []term.Cell
 	if inputMode {
+		runes := []rune(input)
 		cells = term.Cprint("Search ", color.Faint)
+		if mode != SearchFuzzy {
+			cells = append(cells, term.Cprint("("+mode.String()+") ", color.Faint)...)
+		}
 		cells = append(cells, term.Cprint(placeholder+" ", color.Faint)...)
-		cells = append(cells, term.Cprint(input, color.FgWhite)...)
-		cells = append(cells, term.Cprint("█", color.Faint, color.BlinkRapid)...)
-		return cells
+		cells = append(cells, term.Cprint(string(runes[:caret]), color.FgWhite)...)
+		if caret < len(runes) {
+			if !term.ColorEnabled() {
+				// the reverse video is not drawn, mark the caret with a bar
+				cells = append(cells, term.Cell{Ch: '|'})
+			}
+			// draw the caret on top of the character it points to
+			cells = append(cells, term.Cell{Ch: runes[caret], Attr: []color.Attribute{color.ReverseVideo}})
+			cells = append(cells, term.Cprint(string(runes[caret+1:]), color.FgWhite)...)
+			return cells
+		}
+		return append(cells, cursorCell(blink))
 	}
 	cells = term.Cprint(placeholder, color.Faint)
 	if len(input) > 0 {
@@ -76,3 +158,119 @@ func renderSearch(placeholder string, inputMode bool, input string) []term.Cell
 
 	return cells
 }
+
+var (
+	spinnerFrames      = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+	spinnerPlainFrames = []string{"|", "/", "-", "\\"}
+)
+
+// spinnerFrame returns the nth frame of the loading spinner, the plain frames
+// are used if the colors are disabled since the terminal may lack the glyphs
+func spinnerFrame(n int) string {
+	frames := spinnerFrames
+	if !term.ColorEnabled() {
+		frames = spinnerPlainFrames
+	}
+	return frames[n%len(frames)]
+}
+
+// cursorCell returns the block drawn at the end of an input
+func cursorCell(blink bool) term.Cell {
+	if blink {
+		return term.Cell{Ch: '█', Attr: []color.Attribute{color.Faint, color.BlinkRapid}}
+	}
+	return term.Cell{Ch: '█', Attr: []color.Attribute{color.Faint}}
+}
+
+// scrollThumb returns the first row and the number of rows of the scrollbar
+// thumb for a list showing size items from start out of total items
+func scrollThumb(start, size, total int) (int, int) {
+	if total <= size || size <= 0 {
+		return 0, size
+	}
+	length := size * size / total
+	if length < 1 {
+		length = 1
+	}
+	top := start * size / total
+	if top+length > size || start+size >= total {
+		top = size - length
+	}
+	return top, length
+}
+
+// pageOf returns the page of a list showing size items from start and the
+// number of the pages, the last page is the one showing the last item
+func pageOf(start, size, total int) (int, int) {
+	if size <= 0 || total <= size {
+		return 1, 1
+	}
+	pages := (total + size - 1) / size
+	if start+size >= total {
+		return pages, pages
+	}
+	return (start+size-1)/size + 1, pages
+}
+
+// fitCells pads or cuts the line to be exactly width columns wide
+func fitCells(cells []term.Cell, width int) []term.Cell {
+	var col int
+	for i, c := range cells {
+		w := c.Width()
+		if col+w > width {
+			cells = cells[:i]
+			break
+		}
+		col += w
+	}
+	for ; col < width; col++ {
+		cells = append(cells, term.Cell{Ch: ' '})
+	}
+	return cells
+}
+
+// cellsWidth returns the number of the columns of the line
+func cellsWidth(cells []term.Cell) int {
+	var width int
+	for _, c := range cells {
+		width += c.Width()
+	}
+	return width
+}
+
+// scrollItem drops the offset columns of the line that follow its first prefix
+// columns, so that the cursor stays in place while the items are scrolled
+func scrollItem(cells []term.Cell, prefix, offset int) []term.Cell {
+	if offset <= 0 {
+		return cells
+	}
+	var col, i int
+	for ; i < len(cells) && col < prefix; i++ {
+		col += cells[i].Width()
+	}
+	line := append([]term.Cell(nil), cells[:i]...)
+	return append(line, scrollCells(cells[i:], offset)...)
+}
+
+// scrollCells drops the first offset columns of the line. A wide character
+// that would be split by the offset is replaced with a space.
+func scrollCells(cells []term.Cell, offset int) []term.Cell {
+	if offset <= 0 {
+		return cells
+	}
+	var col int
+	for i, c := range cells {
+		w := c.Width()
+		if col+w <= offset {
+			col += w
+			continue
+		}
+		if col < offset {
+			// the wide character starts before the offset, pad its remainder
+			pad := term.Cell{Ch: ' ', Attr: c.Attr}
+			return append([]term.Cell{pad}, cells[i+1:]...)
+		}
+		return cells[i:]
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+	"unicode"
+
+	"github.com/fatih/color"
+	"github.com/isacikgoz/gitin/term"
+)
+
+func TestScrollThumb(t *testing.T) {
+	var tests = []struct {
+		start, size, total int
+		top, length        int
+	}{
+		{0, 5, 3, 0, 5},
+		{0, 5, 5, 0, 5},
+		{0, 5, 10, 0, 2},
+		{5, 5, 10, 3, 2},
+		{0, 5, 1000, 0, 1},
+		{500, 5, 1000, 2, 1},
+		{995, 5, 1000, 4, 1},
+	}
+	for _, test := range tests {
+		top, length := scrollThumb(test.start, test.size, test.total)
+		if top != test.top || length != test.length {
+			t.Errorf("input: %d %d %d\n got: %d %d", test.start, test.size, test.total, top, length)
+		}
+	}
+}
+
+func TestPageOf(t *testing.T) {
+	var tests = []struct {
+		start, size, total int
+		page, pages        int
+	}{
+		{0, 5, 3, 1, 1},
+		{0, 5, 10, 1, 2},
+		{1, 5, 10, 2, 2},
+		{5, 5, 12, 2, 3},
+		{6, 5, 12, 3, 3},
+		{3, 5, 12, 2, 3},
+		{0, 0, 12, 1, 1},
+	}
+	for _, test := range tests {
+		page, pages := pageOf(test.start, test.size, test.total)
+		if page != test.page || pages != test.pages {
+			t.Errorf("input: %d %d %d\n got: %d %d", test.start, test.size, test.total, page, pages)
+		}
+	}
+}
+
+func TestScrollItem(t *testing.T) {
+	var tests = []struct {
+		line   string
+		offset int
+		want   string
+	}{
+		{"> abcdef", 0, "> abcdef"},
+		{"> abcdef", 2, "> cdef"},
+		{"> abcdef", 10, "> "},
+		{"> 世界", 1, ">  界"},
+	}
+	for _, test := range tests {
+		var got strings.Builder
+		for _, c := range scrollItem(term.Cprint(test.line), 2, test.offset) {
+			got.WriteRune(c.Ch)
+		}
+		if got.String() != test.want {
+			t.Errorf("input: %q %d\n got: %q, want %q", test.line, test.offset, got.String(), test.want)
+		}
+	}
+}
+
+func TestHighlightOccurrences(t *testing.T) {
+	var tests = []struct {
+		mode SearchMode
+		term string
+		text string
+		want string
+	}{
+		{SearchFuzzy, "me", "some menu", "..ME.ME.."},
+		{SearchSubstring, "ß", "straße", "....ß."},
+		{SearchRegex, "m.n", "main menu", ".....MEN."},
+		{SearchRegex, "(", "main", "...."},
+	}
+	for _, test := range tests {
+		var got []rune
+		line := highlightOccurrences(term.Cprint(test.text), occurrences(test.mode, test.term), []color.Attribute{color.Underline})
+		for _, c := range line {
+			if len(c.Attr) > 0 {
+				got = append(got, unicode.ToUpper(c.Ch))
+			} else {
+				got = append(got, '.')
+			}
+		}
+		if string(got) != test.want {
+			t.Errorf("mode: %s term: %q\n got: %s want: %s", test.mode, test.term, string(got), test.want)
+		}
+	}
+}
+
+func TestHints(t *testing.T) {
+	list, err := NewList([]string{"a"}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := Create("Items", &Options{QuitKey: 'x'}, list)
+	p.AddKeyBinding(&KeyBinding{Key: 'c', Display: "c", Desc: "commit", Primary: true, Handler: func(interface{}) error { return nil }})
+	p.AddKeyBinding(&KeyBinding{Key: 'm', Display: "m", Desc: "amend", Handler: func(interface{}) error { return nil }})
+	p.AddKeyBinding(&KeyBinding{Key: ' ', Display: "space", Desc: "add/reset", Primary: true,
+		DescFunc: func(item interface{}) string { return "add " + item.(string) },
+		Handler:  func(interface{}) error { return nil }})
+	p.AddKeyBinding(&KeyBinding{Key: 'r', Display: "r", Desc: "reset", Primary: true,
+		Enabled: func(interface{}) bool { return false },
+		Handler: func(interface{}) error { return nil }})
+	var text string
+	for _, c := range genHints(p.hints()) {
+		text += string(c.Ch)
+	}
+	if want := "commit (c)  add a (space)  help (?)  quit (x)"; text != want {
+		t.Errorf("got %q, want %q", text, want)
+	}
+}
+
+func TestHelpOrder(t *testing.T) {
+	list, err := NewList([]string{"a"}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := func(interface{}) error { return nil }
+	var tests = []struct {
+		sortHelp bool
+		want     []string
+	}{
+		{false, []string{"navigation", "zap", "add", "add"}},
+		{true, []string{"add", "add", "navigation", "zap"}},
+	}
+	for _, test := range tests {
+		p := Create("Items", &Options{SortHelp: test.sortHelp}, list)
+		p.AddKeyBinding(&KeyBinding{Key: 'z', Display: "z", Desc: "zap", Handler: handler})
+		p.AddKeyBinding(&KeyBinding{Key: 'a', Display: "a", Desc: "add", Handler: handler})
+		p.AddKeyBinding(&KeyBinding{Key: 'A', Display: "A", Desc: "add", Handler: handler})
+		var descs []string
+		for _, c := range p.allControls() {
+			if c.desc == "navigation" || c.keys == "z" || c.keys == "a" || c.keys == "A" {
+				descs = append(descs, c.desc)
+			}
+		}
+		if strings.Join(descs, ",") != strings.Join(test.want, ",") {
+			t.Errorf("sorted: %t got %v, want %v", test.sortHelp, descs, test.want)
+		}
+	}
+}
+
+func TestGenHelpCategories(t *testing.T) {
+	controls := []control{
+		{keys: "j", desc: "navigation", category: "Navigation"},
+		{keys: "q", desc: "quit", category: generalCategory},
+		{keys: "space", desc: "add", category: "Staging"},
+		{keys: "y", desc: "copy"},
+		{keys: "a", desc: "add", category: "Staging"},
+	}
+	var lines []string
+	for _, line := range genHelp(controls) {
+		var text string
+		for _, c := range line {
+			text += string(c.Ch)
+		}
+		lines = append(lines, text)
+	}
+	want := []string{
+		"Navigation", "  navigation: j", "",
+		"Staging", "  add: space a", "",
+		"General", "  quit: q", "  copy: y", "",
+		"press any key to return.",
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got %q\nwant %q", lines, want)
+	}
+}
@@ -0,0 +1,33 @@
+//go:build !windows
+// +build !windows
+
+package prompt
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// notifyResize sends to the returned channel when the terminal is resized
+func notifyResize(ctx context.Context) <-chan struct{} {
+	sigwinch := make(chan os.Signal, 1)
+	signal.Notify(sigwinch, syscall.SIGWINCH)
+	resized := make(chan struct{}, 1)
+	go func() {
+		defer signal.Stop(sigwinch)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-sigwinch:
+				select {
+				case resized <- struct{}{}:
+				default: // a render is already pending
+				}
+			}
+		}
+	}()
+	return resized
+}
@@ -0,0 +1,36 @@
+//go:build windows
+// +build windows
+
+package prompt
+
+import (
+	"context"
+
+	"github.com/isacikgoz/gitin/term"
+)
+
+// notifyResize sends to the returned channel when the console is resized, a
+// resize record may be read more than once so only the size changes are sent
+func notifyResize(ctx context.Context) <-chan struct{} {
+	resized := make(chan struct{}, 1)
+	go func() {
+		width, height, _ := term.Size()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-term.Resized():
+				w, h, err := term.Size()
+				if err != nil || (w == width && h == height) {
+					continue
+				}
+				width, height = w, h
+				select {
+				case resized <- struct{}{}:
+				default: // a render is already pending
+				}
+			}
+		}
+	}()
+	return resized
+}
@@ -0,0 +1,149 @@
+package prompt
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/isacikgoz/fuzzy"
+)
+
+// SearchMode is the strategy used to match the items against a search term
+type SearchMode int
+
+const (
+	// SearchFuzzy matches the items containing the characters of the term in order
+	SearchFuzzy SearchMode = iota
+	// SearchSubstring matches the items containing the term, ignoring the case
+	SearchSubstring
+	// SearchRegex matches the items with the term as a case-insensitive regular expression
+	SearchRegex
+)
+
+var searchModeNames = []string{"fuzzy", "substring", "regex"}
+
+func (m SearchMode) String() string {
+	if m < 0 || int(m) >= len(searchModeNames) {
+		return fmt.Sprintf("SearchMode(%d)", int(m))
+	}
+	return searchModeNames[m]
+}
+
+// UnmarshalText parses the mode by its name, so that it can be set from the
+// environment
+func (m *SearchMode) UnmarshalText(text []byte) error {
+	for i, name := range searchModeNames {
+		if strings.EqualFold(string(text), name) {
+			*m = SearchMode(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown search mode %q", text)
+}
+
+// next returns the following mode to cycle through the modes
+func (m SearchMode) next() SearchMode {
+	return (m + 1) % SearchMode(len(searchModeNames))
+}
+
+// findFrom matches the items with the given mode. Like fuzzy.FindFrom the
+// results are sent to the returned channel, which is closed when all of the
+// items are matched or the context is cancelled. The fuzzy matches scoring
+// below minScore are dropped unless it is 0, the other modes don't score.
+func findFrom(ctx context.Context, mode SearchMode, term string, data fuzzy.Source, minScore int) <-chan fuzzy.Match {
+	var index func(string) []int
+	switch mode {
+	case SearchSubstring:
+		term = strings.ToLower(term)
+		index = func(s string) []int {
+			if i := strings.Index(strings.ToLower(s), term); i >= 0 {
+				return []int{i, i + len(term)}
+			}
+			return nil
+		}
+	case SearchRegex:
+		re, err := regexp.Compile("(?i)" + term)
+		if err != nil {
+			index = func(string) []int { return nil } // incomplete expression
+		} else {
+			index = re.FindStringIndex
+		}
+	default:
+		if minScore == 0 {
+			return fuzzy.FindFrom(ctx, term, data)
+		}
+		return dropWeak(ctx, fuzzy.FindFrom(ctx, term, data), minScore)
+	}
+
+	matches := make(chan fuzzy.Match)
+	go func() {
+		defer close(matches)
+		for i := 0; i < data.Len(); i++ {
+			str := data.String(i)
+			loc := index(str)
+			if loc == nil {
+				continue
+			}
+			match := fuzzy.Match{Str: str, Index: i}
+			for j := loc[0]; j < loc[1]; j++ {
+				match.MatchedIndexes = append(match.MatchedIndexes, j)
+			}
+			select {
+			case matches <- match:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return matches
+}
+
+// occurrences returns a function that finds the byte ranges of the term in a
+// text, case-insensitively. The fuzzy mode looks for the term as a substring
+// since its scattered characters are meaningless out of the list.
+func occurrences(mode SearchMode, term string) func(string) [][]int {
+	if mode == SearchRegex {
+		re, err := regexp.Compile("(?i)" + term)
+		if err != nil {
+			return func(string) [][]int { return nil }
+		}
+		return func(s string) [][]int { return re.FindAllStringIndex(s, -1) }
+	}
+	term = strings.ToLower(term)
+	return func(s string) [][]int {
+		var locs [][]int
+		s = strings.ToLower(s)
+		for i := 0; len(term) > 0; {
+			j := strings.Index(s[i:], term)
+			if j < 0 {
+				break
+			}
+			locs = append(locs, []int{i + j, i + j + len(term)})
+			i += j + len(term)
+		}
+		return locs
+	}
+}
+
+// dropWeak passes the matches scoring at least minScore, the matcher is
+// drained if the context is cancelled so that it can return
+func dropWeak(ctx context.Context, all <-chan fuzzy.Match, minScore int) <-chan fuzzy.Match {
+	matches := make(chan fuzzy.Match)
+	go func() {
+		defer close(matches)
+		for match := range all {
+			if match.Score < minScore {
+				continue
+			}
+			select {
+			case matches <- match:
+			case <-ctx.Done():
+				for range all {
+				}
+				return
+			}
+		}
+	}()
+	return matches
+}
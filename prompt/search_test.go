@@ -0,0 +1,33 @@
+package prompt
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestFindFrom(t *testing.T) {
+	items := interfaceSource{items: []interface{}{"Fix README", "add readme", "remove tests"}}
+	var tests = []struct {
+		mode    SearchMode
+		term    string
+		indexes []int
+		matches [][]int
+	}{
+		{SearchSubstring, "readme", []int{0, 1}, [][]int{{4, 5, 6, 7, 8, 9}, {4, 5, 6, 7, 8, 9}}},
+		{SearchSubstring, "rdm", nil, nil},
+		{SearchRegex, "^(fix|remove)", []int{0, 2}, [][]int{{0, 1, 2}, {0, 1, 2, 3, 4, 5}}},
+		{SearchRegex, "(", nil, nil},
+	}
+	for _, test := range tests {
+		var indexes []int
+		var matches [][]int
+		for match := range findFrom(context.Background(), test.mode, test.term, items, 0) {
+			indexes = append(indexes, match.Index)
+			matches = append(matches, match.MatchedIndexes)
+		}
+		if !reflect.DeepEqual(indexes, test.indexes) || !reflect.DeepEqual(matches, test.matches) {
+			t.Errorf("mode: %s term: %q\n got: %v %v", test.mode, test.term, indexes, matches)
+		}
+	}
+}
@@ -13,11 +13,21 @@ import (
 	"github.com/isacikgoz/fuzzy"
 )
 
-type interfaceSource []interface{}
+// interfaceSource matches the items by their keys, or by their default format
+// if key is nil
+type interfaceSource struct {
+	items []interface{}
+	key   func(interface{}) string
+}
 
-func (is interfaceSource) String(i int) string { return fmt.Sprint(is[i]) }
+func (is interfaceSource) String(i int) string {
+	if is.key != nil {
+		return is.key(is.items[i])
+	}
+	return fmt.Sprint(is.items[i])
+}
 
-func (is interfaceSource) Len() int { return len(is) }
+func (is interfaceSource) Len() int { return len(is.items) }
 
 // NotFound is an index returned when no item was selected.
 const NotFound = -1
@@ -26,13 +36,29 @@ const NotFound = -1
 // visible items. The list can be moved up, down by one item of time or an
 // entire page (ie: visible size). It keeps track of the current selected item.
 type SyncList struct {
-	items   []interface{}
-	scope   []interface{}
-	matches map[interface{}][]int
-	cursor  int // cursor holds the index of the current selected item
-	size    int // size is the number of visible options
-	start   int
-	find    string
+	items     []interface{}
+	scope     []interface{}
+	matches   map[interface{}][]int
+	cursor    int // cursor holds the index of the current selected item
+	size      int // size is the number of visible options
+	start     int
+	find      string
+	wrap      bool // wrap around when moving past either end
+	mode      SearchMode
+	key       func(interface{}) string    // nil matches the items by fmt.Sprint
+	minScore  int                         // the weaker fuzzy matches are dropped
+	keepOrder bool                        // the matches are not sorted by their scores
+	equal     func(a, b interface{}) bool // nil compares the items with sameItem
+
+	saved      interface{} // the selected item before the search started
+	savedStart int
+
+	less     func(a, b interface{}) bool // nil keeps the items in their order
+	reverse  bool
+	unsorted []interface{} // the items in their original order
+
+	filter func(interface{}) bool // nil lists all of the items
+	pool   []interface{}          // the items passing the filter, searched and listed
 }
 
 // NewList creates and initializes a list of searchable items. The items attribute must be a slice type.
@@ -52,63 +78,258 @@ func NewList(items interface{}, size int) (*SyncList, error) {
 		values[i] = item.Interface()
 	}
 
-	return &SyncList{
+	l := &SyncList{
 		size:  size,
 		items: values,
 		scope: values,
-	}, nil
+		pool:  values,
+	}
+	l.fixCursor()
+	return l, nil
 }
 
 // Prev moves the visible list back one item.
 func (l *SyncList) Prev() {
-	if l.cursor > 0 {
-		l.cursor--
+	i := nextItem(l.scope, l.cursor-1, -1)
+	if i == NotFound && l.wrap {
+		i = nextItem(l.scope, len(l.scope)-1, -1)
 	}
-
-	if l.start > l.cursor {
-		l.start = l.cursor
+	if i != NotFound {
+		l.setCursor(i)
 	}
+	l.fixCursor()
 }
 
-// Search allows the list to be filtered by a given term.
+// Search allows the list to be filtered by a given term. The selection before
+// the search is restored once the term is cleared.
 func (l *SyncList) Search(term string) {
 	term = strings.Trim(term, " ")
+	cleared := len(term) == 0 && len(l.find) > 0
+	if len(term) > 0 && len(l.find) == 0 {
+		l.saved, l.savedStart = l.selected(), l.start
+	}
 	l.cursor = 0
 	l.start = 0
 	l.find = term
 	l.search(term)
+	if cleared {
+		l.restoreSelection()
+		return
+	}
+	l.fixCursor()
 }
 
-// CancelSearch stops the current search and returns the list to its original order.
+// CancelSearch stops the current search and returns the list to its original
+// order, with the item selected before the search.
 func (l *SyncList) CancelSearch() {
-	l.cursor = 0
-	l.start = 0
-	l.scope = l.items
+	if len(l.find) == 0 {
+		return
+	}
+	l.find = ""
+	l.scope = l.pool
+	l.restoreSelection()
+}
+
+// restoreSelection moves the cursor back to the item selected before the
+// search if it is still listed, otherwise to the top
+func (l *SyncList) restoreSelection() {
+	l.cursor, l.start = 0, 0
+	for i, item := range l.scope {
+		if l.same(item, l.saved) {
+			l.start = l.savedStart
+			l.setCursor(i)
+			break
+		}
+	}
+	l.saved = nil
+	l.fixCursor()
+}
+
+// SetSort orders the items by less, a nil less restores their original order.
+// A search is run again and the cursor is kept on the selected item.
+func (l *SyncList) SetSort(less func(a, b interface{}) bool) {
+	l.less = less
+	l.reorder()
+}
+
+// SetReverse lists the items in the reverse order, after they are sorted. A
+// search is run again and the cursor is kept on the selected item.
+func (l *SyncList) SetReverse(reverse bool) {
+	if l.reverse == reverse {
+		return
+	}
+	l.reverse = reverse
+	l.reorder()
+}
+
+// reorder applies the sort and the reverse options to the items
+func (l *SyncList) reorder() {
+	selected := l.selected()
+	if l.unsorted == nil {
+		l.unsorted = l.items
+	}
+	l.items = sortItems(l.unsorted, l.less, l.reverse)
+	if l.less == nil && !l.reverse {
+		l.unsorted = nil
+	}
+	l.pool = filterItems(l.items, l.filter)
+	l.search(l.find)
+	l.keepSelection(selected)
+}
+
+// Filter narrows the list to the items that filter returns true for, e.g. the
+// staged files, regardless of their text. It is combined with the search, a
+// nil filter lists all of the items again. The cursor is kept on the selected
+// item if it passes.
+func (l *SyncList) Filter(filter func(interface{}) bool) {
+	selected := l.selected()
+	l.filter = filter
+	l.pool = filterItems(l.items, filter)
+	l.search(l.find)
+	l.keepSelection(selected)
+}
+
+// selected returns the item under the cursor, nil if the scope is empty
+func (l *SyncList) selected() interface{} {
+	if len(l.scope) == 0 {
+		return nil
+	}
+	return l.scope[l.cursor]
+}
+
+// keepSelection moves the cursor to the item if it is in the scope, otherwise
+// to the top
+func (l *SyncList) keepSelection(item interface{}) {
+	l.cursor, l.start = 0, 0
+	for i, it := range l.scope {
+		if l.same(it, item) {
+			l.SetCursor(i)
+			return
+		}
+	}
+	l.fixCursor()
+}
+
+// fixCursor moves the cursor off a header to the following item, or to the
+// preceding one at the end, and scrolls to the header of the selected item if
+// it is just out of the view
+func (l *SyncList) fixCursor() {
+	if len(l.scope) == 0 {
+		return
+	}
+	if isHeader(l.scope[l.cursor]) {
+		i := nextItem(l.scope, l.cursor, 1)
+		if i == NotFound {
+			i = nextItem(l.scope, l.cursor, -1)
+		}
+		if i != NotFound {
+			l.setCursor(i)
+		}
+	}
+	if l.size > 1 && l.cursor > 0 && l.start == l.cursor && isHeader(l.scope[l.cursor-1]) {
+		l.start--
+	}
+}
+
+// sortItems returns a sorted and optionally reversed copy of the items, the
+// items are returned as is if there is nothing to do
+func sortItems(items []interface{}, less func(a, b interface{}) bool, reverse bool) []interface{} {
+	if less == nil && !reverse {
+		return items
+	}
+	sorted := make([]interface{}, len(items))
+	copy(sorted, items)
+	if less != nil {
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return less(sorted[i], sorted[j])
+		})
+	}
+	if reverse {
+		for i, j := 0, len(sorted)-1; i < j; i, j = i+1, j-1 {
+			sorted[i], sorted[j] = sorted[j], sorted[i]
+		}
+	}
+	return sorted
 }
 
 func (l *SyncList) search(term string) {
 	if len(term) == 0 {
-		l.scope = l.items
+		l.scope = l.pool
 		return
 	}
 	l.matches = make(map[interface{}][]int)
-	matches := fuzzy.FindFrom(context.Background(), term, interfaceSource(l.items))
+	matches := findFrom(context.Background(), l.mode, term, interfaceSource{l.pool, l.key}, l.minScore)
 
 	results := make([]fuzzy.Match, 0)
 	for match := range matches {
 		results = append(results, match)
 	}
 
-	sort.Stable(fuzzy.Sortable(results))
+	if !l.keepOrder {
+		sort.Stable(fuzzy.Sortable(results))
+	}
 
 	l.scope = make([]interface{}, 0)
 	for _, r := range results {
-		item := l.items[r.Index]
+		item := l.pool[r.Index]
+		if isHeader(item) {
+			continue
+		}
 		l.scope = append(l.scope, item)
 		l.matches[item] = r.MatchedIndexes
 	}
 }
 
+// SetSearchMode changes the matching strategy and filters the list again with
+// the current term.
+func (l *SyncList) SetSearchMode(mode SearchMode) {
+	if l.mode == mode {
+		return
+	}
+	l.mode = mode
+	if len(l.find) > 0 {
+		l.Search(l.find)
+	}
+}
+
+// SetSearchKey makes the search match the items by the text that key returns
+// instead of their default format, e.g. only the name of a file while a longer
+// line is displayed. The matched indexes are the offsets in the key. The list
+// is filtered again with the current term.
+func (l *SyncList) SetSearchKey(key func(interface{}) string) {
+	l.key = key
+	if len(l.find) > 0 {
+		l.Search(l.find)
+	}
+}
+
+// SetEqual replaces the comparison of the items that keeps the selection, e.g.
+// to compare the value items by one of their fields
+func (l *SyncList) SetEqual(equal func(a, b interface{}) bool) {
+	l.equal = equal
+}
+
+// same returns true if the items are the same item
+func (l *SyncList) same(a, b interface{}) bool {
+	if l.equal != nil {
+		return l.equal(a, b)
+	}
+	return sameItem(a, b)
+}
+
+// SetScoring drops the fuzzy matches scoring below minScore, 0 keeps all of
+// them, and lists the matches in the order of the items instead of by their
+// scores if keepOrder is set. The list is filtered again with the current term.
+func (l *SyncList) SetScoring(minScore int, keepOrder bool) {
+	if l.minScore == minScore && l.keepOrder == keepOrder {
+		return
+	}
+	l.minScore, l.keepOrder = minScore, keepOrder
+	if len(l.find) > 0 {
+		l.Search(l.find)
+	}
+}
+
 // Start returns the current render start position of the list.
 func (l *SyncList) Start() int {
 	return l.start
@@ -127,8 +348,13 @@ func (l *SyncList) SetStart(i int) {
 }
 
 // SetCursor sets the position of the cursor in the list. Values out of bounds will
-// be clamped.
+// be clamped, a header moves the cursor to the following item.
 func (l *SyncList) SetCursor(i int) {
+	l.setCursor(i)
+	l.fixCursor()
+}
+
+func (l *SyncList) setCursor(i int) {
 	max := len(l.scope) - 1
 	if i >= max {
 		i = max
@@ -147,15 +373,14 @@ func (l *SyncList) SetCursor(i int) {
 
 // Next moves the visible list forward one item.
 func (l *SyncList) Next() {
-	max := len(l.scope) - 1
-
-	if l.cursor < max {
-		l.cursor++
+	i := nextItem(l.scope, l.cursor+1, 1)
+	if i == NotFound && l.wrap {
+		i = nextItem(l.scope, 0, 1)
 	}
-
-	if l.start+l.size <= l.cursor {
-		l.start = l.cursor - l.size + 1
+	if i != NotFound {
+		l.setCursor(i)
 	}
+	l.fixCursor()
 }
 
 // PageUp moves the visible list backward by x items. Where x is the size of the
@@ -173,6 +398,7 @@ func (l *SyncList) PageUp() {
 	if cursor < l.cursor {
 		l.cursor = cursor
 	}
+	l.fixCursor()
 }
 
 // PageDown moves the visible list forward by x items. Where x is the size of
@@ -197,6 +423,7 @@ func (l *SyncList) PageDown() {
 	} else if cursor > l.cursor {
 		l.cursor = cursor
 	}
+	l.fixCursor()
 }
 
 // CanPageDown returns whether a list can still PageDown().
@@ -218,7 +445,7 @@ func (l *SyncList) Index() int {
 	selected := l.scope[l.cursor]
 
 	for i, item := range l.items {
-		if item == selected {
+		if l.same(item, selected) {
 			return i
 		}
 	}
@@ -254,6 +481,47 @@ func (l *SyncList) Size() int {
 	return l.size
 }
 
+// SetSize changes the number of the visible items, the list is scrolled to
+// keep the cursor visible and to fill the rows if it has grown.
+func (l *SyncList) SetSize(size int) {
+	if size < 1 {
+		return
+	}
+	l.size = size
+	if l.start+l.size <= l.cursor {
+		l.start = l.cursor - l.size + 1
+	}
+	if l.start+l.size > len(l.scope) {
+		if l.start = len(l.scope) - l.size; l.start < 0 {
+			l.start = 0
+		}
+	}
+}
+
+// SetWrapScroll makes Next and Prev wrap around at the ends of the list.
+func (l *SyncList) SetWrapScroll(wrap bool) {
+	l.wrap = wrap
+}
+
+func (l *SyncList) Len() int {
+	return len(l.scope)
+}
+
+// Total is the number of the items regardless of the search, the headers are
+// not counted
+func (l *SyncList) Total() int {
+	return countItems(l.items)
+}
+
+// Position returns the 1-based position of the selected item and the number of
+// the items in the scope, the headers are not counted
+func (l *SyncList) Position() (int, int) {
+	if len(l.scope) == 0 {
+		return 0, 0
+	}
+	return countItems(l.scope[:l.cursor+1]), countItems(l.scope)
+}
+
 func (l *SyncList) Cursor() int {
 	return l.cursor
 }
@@ -262,6 +530,22 @@ func (l *SyncList) Matches(item interface{}) []int {
 	return l.matches[item]
 }
 
+// Find returns the position of the first item in the scope that match returns
+// true for, or NotFound if there is none
+func (l *SyncList) Find(match func(item interface{}) bool) int {
+	for i, item := range l.scope {
+		if match(item) {
+			return i
+		}
+	}
+	return NotFound
+}
+
+// Loading is always false since the items are known at the creation.
+func (l *SyncList) Loading() bool {
+	return false
+}
+
 func (l *SyncList) Update() chan struct{} {
 	return nil
 }
@@ -0,0 +1,175 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type testHeader string
+
+func (h testHeader) Header() bool { return true }
+
+func TestSyncListHeaders(t *testing.T) {
+	items := []interface{}{testHeader("A"), "a1", "a2", testHeader("B"), "b1"}
+	var tests = []struct {
+		name string
+		move func(l *SyncList)
+		want interface{}
+	}{
+		{"initial", func(l *SyncList) {}, "a1"},
+		{"next over header", func(l *SyncList) { l.Next(); l.Next() }, "b1"},
+		{"prev over header", func(l *SyncList) { l.SetCursor(4); l.Prev() }, "a2"},
+		{"prev at top", func(l *SyncList) { l.Prev() }, "a1"},
+		{"cursor on header", func(l *SyncList) { l.SetCursor(3) }, "b1"},
+		{"search skips headers", func(l *SyncList) { l.Search("B") }, "b1"},
+	}
+	for _, test := range tests {
+		l, err := NewList(items, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		test.move(l)
+		visible, idx := l.Items()
+		if idx == NotFound || visible[idx] != test.want {
+			t.Errorf("%s: got %v selected in %v, want %v", test.name, idx, visible, test.want)
+		}
+	}
+}
+
+func TestSyncListSearchKey(t *testing.T) {
+	l, err := NewList([]string{"a/main.go", "main/a.go", "b/x.go"}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.SetSearchMode(SearchSubstring)
+	l.Search("main")
+	if l.Len() != 2 {
+		t.Errorf("got %d items matching the whole path, want 2", l.Len())
+	}
+	l.SetSearchKey(func(item interface{}) string {
+		s := item.(string)
+		return s[strings.LastIndex(s, "/")+1:]
+	})
+	visible, _ := l.Items()
+	if len(visible) != 1 || visible[0] != "a/main.go" {
+		t.Fatalf("got %v matching the file names, want [a/main.go]", visible)
+	}
+	if matches := l.Matches(visible[0]); len(matches) != 4 || matches[0] != 0 {
+		t.Errorf("got matches %v, want the offsets in the key", matches)
+	}
+}
+
+func TestSyncListScoring(t *testing.T) {
+	items := []string{"domain.txt", "my_awesome_image_note", "main.go", "README"}
+	var tests = []struct {
+		minScore  int
+		keepOrder bool
+		want      string
+	}{
+		{0, false, "main.go my_awesome_image_note domain.txt"},
+		{0, true, "domain.txt my_awesome_image_note main.go"},
+		{50, false, "main.go my_awesome_image_note"},
+		{60, true, "main.go"},
+	}
+	for _, test := range tests {
+		l, err := NewList(items, 4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		l.SetScoring(test.minScore, test.keepOrder)
+		l.Search("main")
+		visible, _ := l.Items()
+		if got := strings.Join(toStrings(visible), " "); got != test.want {
+			t.Errorf("min score: %d keep order: %t\n got %q, want %q", test.minScore, test.keepOrder, got, test.want)
+		}
+	}
+}
+
+func TestSyncListRestoresSelection(t *testing.T) {
+	items := []string{"a", "b", "c", "d", "e", "f", "g"}
+	var tests = []struct {
+		name   string
+		cancel func(l *SyncList)
+	}{
+		{"cancel", func(l *SyncList) { l.CancelSearch() }},
+		{"clear", func(l *SyncList) { l.Search("") }},
+	}
+	for _, test := range tests {
+		l, err := NewList(items, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		l.SetCursor(5)
+		start := l.Start()
+		l.Search("b")
+		l.Search("bc") // refining the term doesn't replace the selection
+		test.cancel(l)
+		if l.Cursor() != 5 || l.Start() != start {
+			t.Errorf("%s: cursor %d start %d, want %d %d", test.name, l.Cursor(), l.Start(), 5, start)
+		}
+	}
+}
+
+func TestSyncListFilter(t *testing.T) {
+	items := []interface{}{testHeader("A"), "a1", "a2", testHeader("B"), "b1", "b2", testHeader("C"), "c1"}
+	second := func(item interface{}) bool { return strings.HasSuffix(item.(string), "2") }
+	var tests = []struct {
+		name   string
+		filter func(interface{}) bool
+		term   string
+		want   string
+	}{
+		{"filter", second, "", "A a2 B b2"},
+		{"filter and search", second, "b", "b2"},
+		{"search", nil, "b1", "b1"},
+		{"cleared", nil, "", "A a1 a2 B b1 b2 C c1"},
+	}
+	l, err := NewList(items, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Next() // a2 passes each of the filters
+	for _, test := range tests {
+		l.Filter(test.filter)
+		l.Search(test.term)
+		visible, _ := l.Items()
+		got := make([]string, len(visible))
+		for i, item := range visible {
+			got[i] = fmt.Sprint(item)
+		}
+		if strings.Join(got, " ") != test.want {
+			t.Errorf("%s: got %v, want %s", test.name, got, test.want)
+		}
+	}
+	if visible, idx := l.Items(); visible[idx] != "a2" {
+		t.Errorf("got %v selected after the filter is cleared, want a2", visible[idx])
+	}
+}
+
+func TestNewListSize(t *testing.T) {
+	var tests = []struct {
+		size int
+		fail bool
+	}{
+		{-1, true},
+		{0, true},
+		{1, false},
+		{3, false},
+		{10, false}, // larger than the items
+	}
+	for _, test := range tests {
+		l, err := NewList([]string{"a", "b", "c"}, test.size)
+		if (err != nil) != test.fail {
+			t.Errorf("size: %d error: %v", test.size, err)
+		}
+		if err != nil {
+			continue
+		}
+		l.Next()
+		l.Next()
+		if visible, idx := l.Items(); idx == NotFound || visible[idx] != "c" {
+			t.Errorf("size: %d got %v with %d selected, want c", test.size, visible, idx)
+		}
+	}
+}
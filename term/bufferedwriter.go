@@ -7,10 +7,13 @@ import (
 	"bytes"
 	"fmt"
 	"io"
-
-	"github.com/fatih/color"
+	"regexp"
+	"strings"
 )
 
+// escapeSequence matches the control sequences like colors and cursor moves
+var escapeSequence = regexp.MustCompile(`\x1b\[[0-9;?]*[a-zA-Z]`)
+
 // BufferedWriter creates, clears and, moves up or down lines as needed to write
 // the output to the terminal using ANSI escape codes.
 type BufferedWriter struct {
@@ -20,6 +23,7 @@ type BufferedWriter struct {
 	reset    bool
 	cursor   int
 	height   int
+	frame    [][]byte // the lines written since the last flush
 }
 
 // NewBufferedWriter creates and initializes a new BufferedWriter.
@@ -45,6 +49,11 @@ func (b *BufferedWriter) Write(bites []byte) (int, error) {
 		defer b.buf.Write([]byte(lwon))
 	}
 
+	if b.cursor == 0 {
+		b.frame = b.frame[:0] // a new frame starts
+	}
+	b.frame = append(b.frame, append([]byte(nil), bites...))
+
 	if b.reset {
 		for i := 0; i < b.height; i++ {
 			_, err := b.buf.Write(moveUp)
@@ -97,19 +106,7 @@ func (b *BufferedWriter) Write(bites []byte) (int, error) {
 
 // WriteCells add colored text to the inner buffer
 func (b *BufferedWriter) WriteCells(cs []Cell) (int, error) {
-	bs := make([]byte, 0)
-	if colored {
-		for _, c := range cs {
-			paint := color.New(c.Attr...)
-			painted := paint.Sprintf(string(c.Ch))
-			bs = append(bs, []byte(painted)...)
-		}
-	} else {
-		for _, c := range cs {
-			bs = append(bs, []byte(string(c.Ch))...)
-		}
-	}
-	return b.Write(bs)
+	return b.Write([]byte(Sprint(cs)))
 }
 
 // Flush writes any buffered data to the underlying io.Writer, ensuring that any pending data is displayed.
@@ -152,6 +149,20 @@ func (b *BufferedWriter) Flush() error {
 	return nil
 }
 
+// Frame returns the lines of the last frame, which are the lines written after
+// the previous Flush. The escape sequences like the colors are stripped unless
+// ansi is set. It is meant to inspect what is displayed, e.g. in snapshot tests.
+func (b *BufferedWriter) Frame(ansi bool) string {
+	lines := make([]string, 0, len(b.frame))
+	for _, line := range b.frame {
+		if !ansi {
+			line = escapeSequence.ReplaceAll(line, nil)
+		}
+		lines = append(lines, string(line))
+	}
+	return strings.Join(lines, "\n")
+}
+
 // ClearScreen solves problems (R) and use it after Reset()
 func (b *BufferedWriter) ClearScreen() error {
 	for i := 0; i < b.height; i++ {
@@ -181,6 +192,12 @@ func (b *BufferedWriter) ShowCursor() {
 	_, _ = b.w.Write([]byte(showCursor))
 }
 
+// Copy sets the clipboard of the terminal to the text like SetClipboard, the
+// sequence is written to the underlying io.Writer without buffering
+func (b *BufferedWriter) Copy(text string) error {
+	return writeClipboard(b.w, text)
+}
+
 // HideCursor writes to os.Stdout that to hide cursor
 func (b *BufferedWriter) HideCursor() {
 	_, _ = b.w.Write([]byte(hideCursor))
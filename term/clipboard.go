@@ -0,0 +1,47 @@
+package term
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// clipboardLimit is the size of the encoded text that the terminals accept, the
+// longer sequences are dropped by some of them like tmux
+const clipboardLimit = 100000
+
+var clipboard = true
+
+// ErrClipboardDisabled is returned by SetClipboard if DisableClipboard is called
+var ErrClipboardDisabled = errors.New("clipboard is disabled")
+
+// DisableClipboard makes SetClipboard fail without writing anything, since some
+// terminals flash or ask for permission when a program sets the clipboard
+func DisableClipboard() {
+	clipboard = false
+}
+
+// SetClipboard sets the clipboard of the terminal to the text with the OSC 52
+// sequence. It works over ssh as well since no program is needed on the host,
+// the terminal must be initialized.
+func SetClipboard(text string) error {
+	if writer == nil {
+		return errors.New("terminal is not initialized")
+	}
+	return writeClipboard(writer, text)
+}
+
+// writeClipboard writes the OSC 52 sequence of the text, the text is base64
+// encoded as the sequence requires
+func writeClipboard(w io.Writer, text string) error {
+	if !clipboard {
+		return ErrClipboardDisabled
+	}
+	payload := base64.StdEncoding.EncodeToString([]byte(text))
+	if len(payload) > clipboardLimit {
+		return fmt.Errorf("%d bytes are too large for the clipboard", len(text))
+	}
+	_, err := io.WriteString(w, "\x1b]52;c;"+payload+"\a")
+	return err
+}
@@ -0,0 +1,71 @@
+package term
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// RGB is a 24-bit color of a cell, it is drawn with the closest of the 16 ANSI
+// colors if the terminal doesn't support true colors
+type RGB struct {
+	R, G, B uint8
+}
+
+// trueColor is set if the terminal announces the 24-bit color support
+var trueColor = os.Getenv("COLORTERM") == "truecolor" || os.Getenv("COLORTERM") == "24bit"
+
+// ansiPalette is the xterm palette of the 16 ANSI colors in the order of the
+// foreground attributes, i.e. FgBlack to FgWhite and then FgHiBlack to FgHiWhite
+var ansiPalette = []RGB{
+	{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0},
+	{0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+	{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0},
+	{92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+// nearest returns the foreground attribute of the closest ANSI color
+func (c RGB) nearest() color.Attribute {
+	best, dist := 0, -1
+	for i, p := range ansiPalette {
+		dr, dg, db := int(c.R)-int(p.R), int(c.G)-int(p.G), int(c.B)-int(p.B)
+		if d := dr*dr + dg*dg + db*db; dist < 0 || d < dist {
+			best, dist = i, d
+		}
+	}
+	if best < 8 {
+		return color.FgBlack + color.Attribute(best)
+	}
+	return color.FgHiBlack + color.Attribute(best-8)
+}
+
+// paint returns the colored text of the cell
+func (c Cell) paint() string {
+	attrs := c.Attr
+	var params []string
+	if c.Fg != nil {
+		if trueColor {
+			params = append(params, fmt.Sprintf("38;2;%d;%d;%d", c.Fg.R, c.Fg.G, c.Fg.B))
+		} else {
+			attrs = append(attrs[:len(attrs):len(attrs)], c.Fg.nearest())
+		}
+	}
+	if c.Bg != nil {
+		if trueColor {
+			params = append(params, fmt.Sprintf("48;2;%d;%d;%d", c.Bg.R, c.Bg.G, c.Bg.B))
+		} else {
+			// background attributes are 10 greater than the foreground ones
+			attrs = append(attrs[:len(attrs):len(attrs)], c.Bg.nearest()+10)
+		}
+	}
+	if len(params) == 0 || color.NoColor {
+		return color.New(attrs...).Sprint(string(c.Ch))
+	}
+	for _, attr := range attrs {
+		params = append(params, strconv.Itoa(int(attr)))
+	}
+	return esc + strings.Join(params, ";") + "m" + string(c.Ch) + esc + "0m"
+}
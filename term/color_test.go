@@ -0,0 +1,60 @@
+package term
+
+import (
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestNearest(t *testing.T) {
+	var tests = []struct {
+		rgb  RGB
+		want color.Attribute
+	}{
+		{RGB{0, 0, 0}, color.FgBlack},
+		{RGB{200, 10, 0}, color.FgRed},
+		{RGB{250, 10, 10}, color.FgHiRed},
+		{RGB{0, 0, 230}, color.FgBlue},
+		{RGB{90, 90, 250}, color.FgHiBlue},
+		{RGB{128, 128, 128}, color.FgHiBlack},
+		{RGB{230, 230, 230}, color.FgWhite},
+		{RGB{250, 250, 250}, color.FgHiWhite},
+	}
+	for _, test := range tests {
+		if got := test.rgb.nearest(); got != test.want {
+			t.Errorf("input: %v\n got: %d want: %d", test.rgb, got, test.want)
+		}
+	}
+}
+
+func TestPaint(t *testing.T) {
+	noColor, truecolor := color.NoColor, trueColor
+	defer func() { color.NoColor, trueColor = noColor, truecolor }()
+	color.NoColor = false // the output of the tests is not a terminal
+
+	var tests = []struct {
+		cell      Cell
+		truecolor bool
+		want      string
+	}{
+		{Cell{Ch: 'x', Attr: []color.Attribute{color.Bold}}, true, "\x1b[1mx\x1b[0m"},
+		{Cell{Ch: 'x', Fg: &RGB{1, 2, 3}}, true, "\x1b[38;2;1;2;3mx\x1b[0m"},
+		{Cell{Ch: 'x', Fg: &RGB{1, 2, 3}, Bg: &RGB{4, 5, 6}, Attr: []color.Attribute{color.Bold}}, true, "\x1b[38;2;1;2;3;48;2;4;5;6;1mx\x1b[0m"},
+		{Cell{Ch: 'x', Fg: &RGB{205, 0, 0}}, false, "\x1b[31mx\x1b[0m"},
+		{Cell{Ch: 'x', Fg: &RGB{205, 0, 0}, Bg: &RGB{0, 0, 238}, Attr: []color.Attribute{color.Bold}}, false, "\x1b[1;31;44mx\x1b[0m"},
+	}
+	for _, test := range tests {
+		trueColor = test.truecolor
+		if got := test.cell.paint(); got != test.want {
+			t.Errorf("input: %+v %v\n got: %q want: %q", test.cell, test.truecolor, got, test.want)
+		}
+	}
+
+	// the colors are dropped along with the attributes
+	defer func(enabled bool) { colored = enabled }(colored)
+	DisableColor()
+	cells := []Cell{{Ch: 'x', Fg: &RGB{1, 2, 3}, Attr: []color.Attribute{color.Bold}}}
+	if got := Sprint(cells); got != "x" {
+		t.Errorf("got %q with the colors disabled, want %q", got, "x")
+	}
+}
@@ -10,6 +10,16 @@ const (
 	lwoff = "\x1b[?7l"
 	// LineWrapOn restores the linewrap setting
 	lwon = "\x1b[?7h"
+	// altScreenOn switches to the alternate screen buffer
+	altScreenOn = "\x1b[?1049h"
+	// altScreenOff restores the main screen buffer with its scrollback
+	altScreenOff = "\x1b[?1049l"
+	// pasteOn makes the terminal to mark the pasted text
+	pasteOn = "\x1b[?2004h"
+	// pasteOff restores the normal paste behavior
+	pasteOff = "\x1b[?2004l"
+	// pasteEnd is sent after the pasted text
+	pasteEnd = "\x1b[201~"
 )
 
 var (
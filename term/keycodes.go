@@ -13,6 +13,31 @@ const (
 	Backspace2 = rune(KeyDEL)
 )
 
+// These are the keys without a control character equivalent. They are mapped
+// to the unicode private use area so that they can't collide with typed text.
+const (
+	ShiftArrowLeft rune = 0xE000 + iota
+	ShiftArrowRight
+	PasteStart // the text is read with ReadPaste
+	Home
+	End
+	PageUp
+	PageDown
+	Delete
+	F1
+	F2
+	F3
+	F4
+	F5
+	F6
+	F7
+	F8
+	F9
+	F10
+	F11
+	F12
+)
+
 // Key is the ascii codes of a keys
 type Key int16
 
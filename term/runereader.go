@@ -1,73 +1,238 @@
-//go:build !windows
-// +build !windows
-
 // This is a modified version of survey's runereader. The original version can
 // be found at https://github.com/AlecAivazis/survey
 
 package term
 
 import (
-	"fmt"
+	"bufio"
+	"io"
+	"strings"
+	"time"
 )
 
+// escTimeout is how long the rest of an escape sequence is waited for, since
+// the terminal may write a sequence in pieces. A lone esc is followed by
+// nothing within that time.
+const escTimeout = 50 * time.Millisecond
+
 // RuneReader reads from an io.Reader interface
 type RuneReader struct {
-	in Reader
+	in    io.Reader
+	buf   *bufio.Reader
+	ready func(timeout time.Duration) bool // nil if the input can't be waited for
+	wake  *waker                           // nil if the wait can't be cancelled
+}
+
+// NewRuneReader creates a new instance of RuneReader, the reader is usually the
+// stdin but it can be any reader e.g. to replay the keys in a test. The escape
+// sequences are only waited for if the reader is a file like the stdin, the
+// other readers should write the sequences at once.
+func NewRuneReader(reader io.Reader) *RuneReader {
+	rr := &RuneReader{
+		in:  reader,
+		buf: bufio.NewReader(reader),
+	}
+	if f, ok := reader.(interface{ Fd() uintptr }); ok {
+		fd := f.Fd()
+		// a reader without a waker still works, its wait just can't be cancelled
+		rr.wake, _ = newWaker()
+		rr.ready = func(timeout time.Duration) bool { return inputReady(fd, rr.wake, timeout) }
+	}
+	return rr
+}
+
+// more returns true if the next byte of an escape sequence can be read without
+// blocking, it waits for the byte for escTimeout
+func (rr *RuneReader) more() bool {
+	if rr.buf.Buffered() > 0 {
+		return true
+	}
+	return rr.ready != nil && rr.ready(escTimeout)
+}
+
+// Wait blocks until there is input to read or the wait is cancelled, it returns
+// at once if the reader can't be waited for
+func (rr *RuneReader) Wait() {
+	if rr.buf.Buffered() == 0 && rr.ready != nil {
+		if !rr.ready(-1) && rr.wake != nil {
+			rr.wake.drain()
+		}
+	}
 }
 
-// NewRuneReader creates a new instance of RuneReader
-func NewRuneReader(reader Reader) *RuneReader {
-	return &RuneReader{
-		in: reader,
+// Cancel makes a blocked Wait return, or the next one if none is blocked
+func (rr *RuneReader) Cancel() {
+	if rr.wake != nil {
+		rr.wake.wake()
 	}
 }
 
-// ReadRune returns a single rune from the stdin
+// Ready returns true if there is input to read without blocking, the readers
+// that can't be waited for are always ready
+func (rr *RuneReader) Ready() bool {
+	return rr.buf.Buffered() > 0 || rr.ready == nil || rr.ready(0)
+}
+
+// ReadRune returns a single rune from the stdin, an escape sequence like an
+// arrow key is returned as a single key
 func (rr *RuneReader) ReadRune() (rune, int, error) {
-	r, size, err := state.reader.ReadRune()
+	r, size, err := rr.buf.ReadRune()
+	if err != nil || r != '\033' {
+		return r, size, err
+	}
+	if !rr.more() {
+		return rune(KeyESC), 1, nil
+	}
+	r, size, err = rr.buf.ReadRune()
 	if err != nil {
 		return r, size, err
 	}
-
-	// parse ^[ sequences to look for arrow keys
-	if r == '\033' {
-		if state.reader.Buffered() == 0 {
-			// no more characters so must be `Esc` key
-			return rune(KeyESC), 1, nil
-		}
-		r, size, err = state.reader.ReadRune()
+	switch {
+	case r == 'O' && rr.more():
+		// some terminals send the arrow keys as ^[O sequences
+		r, _, err = rr.buf.ReadRune()
 		if err != nil {
 			return r, size, err
 		}
-		if r != '[' {
-			return r, size, fmt.Errorf("Unexpected Escape Sequence: %q", []rune{'\033', r})
+		return ss3Key(r), 1, nil
+	case r == '[' && rr.more():
+		params, final, err := rr.readSequence()
+		if err != nil {
+			return final, 1, err
 		}
-		r, size, err = state.reader.ReadRune()
+		return csiKey(params, final), 1, nil
+	}
+	// not a sequence, e.g. esc is pressed just before another key
+	if err := rr.buf.UnreadRune(); err != nil {
+		return r, size, err
+	}
+	return rune(KeyESC), 1, nil
+}
+
+// readSequence consumes the rest of a control sequence after ^[[ and returns
+// its parameters and its final byte. The final byte is 0 if the sequence is
+// incomplete, and the mouse reports are consumed as a whole so that their
+// coordinates aren't read as keys.
+func (rr *RuneReader) readSequence() (string, rune, error) {
+	var params []rune
+	for {
+		r, _, err := rr.buf.ReadRune()
 		if err != nil {
-			return r, size, err
+			return "", r, err
+		}
+		switch {
+		case r >= 0x30 && r <= 0x3f: // parameter bytes like digits and ;
+			params = append(params, r)
+		case r >= 0x20 && r <= 0x2f: // intermediate bytes
+		case r == 'M' && len(params) == 0:
+			// a legacy mouse report is followed by three bytes
+			for i := 0; i < 3 && rr.more(); i++ {
+				if _, _, err := rr.buf.ReadRune(); err != nil {
+					return "", 0, err
+				}
+			}
+			return "", 0, nil
+		default:
+			return string(params), r, nil
+		}
+		if !rr.more() {
+			return string(params), 0, nil
+		}
+	}
+}
+
+// tildeKeys are the keys sent as ^[[<n>~, the alternatives of home and end are
+// sent by rxvt and the linux console
+var tildeKeys = map[string]rune{
+	"1":   Home,
+	"3":   Delete,
+	"4":   End,
+	"5":   PageUp,
+	"6":   PageDown,
+	"7":   Home,
+	"8":   End,
+	"11":  F1,
+	"12":  F2,
+	"13":  F3,
+	"14":  F4,
+	"15":  F5,
+	"17":  F6,
+	"18":  F7,
+	"19":  F8,
+	"20":  F9,
+	"21":  F10,
+	"23":  F11,
+	"24":  F12,
+	"200": PasteStart, // start of a bracketed paste
+}
+
+// csiKey maps a control sequence to a key, the unknown ones like the mouse
+// reports are mapped to ctrl-space which is ignored by the prompt
+func csiKey(params string, final rune) rune {
+	if final == '~' {
+		if key, ok := tildeKeys[params]; ok {
+			return key
 		}
-		switch r {
+		return rune(KeyCtrlSpace)
+	}
+	// modified keys are sent with parameters e.g. ^[[1;2C for shift+right
+	switch params {
+	case "":
+	case "1;2":
+		switch final {
 		case 'D':
-			return ArrowLeft, 1, nil
+			return ShiftArrowLeft
 		case 'C':
-			return ArrowRight, 1, nil
-		case 'A':
-			return ArrowUp, 1, nil
-		case 'B':
-			return ArrowDown, 1, nil
-		case 'H': // Home button
-			return rune(KeyCtrlA), 1, nil
-		case 'F': // End button
-			return rune(KeyCtrlQ), 1, nil
-		case '3': // Delete Button
-			// discard the following '~' key from buffer
-			_, _ = state.reader.Discard(1)
-			return rune(KeyCtrlR), 1, nil
-		default:
-			// discard the following '~' key from buffer
-			_, _ = state.reader.Discard(1)
-			return rune(KeyCtrlSpace), 1, nil
+			return ShiftArrowRight
+		}
+		return rune(KeyCtrlSpace)
+	default:
+		return rune(KeyCtrlSpace)
+	}
+	return ss3Key(final)
+}
+
+// ss3Key maps the final byte of a ^[O sequence to a key, the same final bytes
+// are used by the control sequences without parameters
+func ss3Key(r rune) rune {
+	switch r {
+	case 'D':
+		return ArrowLeft
+	case 'C':
+		return ArrowRight
+	case 'A':
+		return ArrowUp
+	case 'B':
+		return ArrowDown
+	case 'H':
+		return Home
+	case 'F':
+		return End
+	case 'P':
+		return F1
+	case 'Q':
+		return F2
+	case 'R':
+		return F3
+	case 'S':
+		return F4
+	default:
+		return rune(KeyCtrlSpace)
+	}
+}
+
+// ReadPaste reads the pasted text until the end marker, it should be called
+// after ReadRune returns PasteStart
+func (rr *RuneReader) ReadPaste() (string, error) {
+	var text []rune
+	for {
+		r, _, err := rr.buf.ReadRune()
+		if err != nil {
+			return string(text), err
+		}
+		text = append(text, r)
+		if r == '~' && strings.HasSuffix(string(text), pasteEnd) {
+			return strings.TrimSuffix(string(text), pasteEnd), nil
 		}
 	}
-	return r, size, err
 }
@@ -0,0 +1,100 @@
+package term
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadRune(t *testing.T) {
+	var tests = []struct {
+		input string
+		keys  []rune
+	}{
+		{"\x1b[A\x1bOB", []rune{ArrowUp, ArrowDown}},
+		{"\x1b[1;2Cx", []rune{ShiftArrowRight, 'x'}},
+		{"\x1b", []rune{rune(KeyESC)}},
+		{"\x1bj", []rune{rune(KeyESC), 'j'}},
+		{"\x1b[<0;12;5Mq", []rune{rune(KeyCtrlSpace), 'q'}}, // mouse report
+		{"\x1b[M !!q", []rune{rune(KeyCtrlSpace), 'q'}},
+		{"\x1b[H\x1b[F", []rune{Home, End}},
+		{"\x1b[1~\x1b[4~\x1b[7~\x1b[8~", []rune{Home, End, Home, End}},
+		{"\x1b[5~\x1b[6~\x1b[3~", []rune{PageUp, PageDown, Delete}},
+		{"\x1bOP\x1b[15~\x1b[24~\x1b[99~", []rune{F1, F5, F12, rune(KeyCtrlSpace)}},
+	}
+	for _, test := range tests {
+		rr := NewRuneReader(strings.NewReader(test.input))
+		var keys []rune
+		for range test.keys {
+			r, _, err := rr.ReadRune()
+			if err != nil {
+				break
+			}
+			keys = append(keys, r)
+		}
+		if string(keys) != string(test.keys) {
+			t.Errorf("input: %q\n got: %q want: %q", test.input, keys, test.keys)
+		}
+	}
+}
+
+func TestReadRuneSplitSequence(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	rr := NewRuneReader(r)
+
+	go func() {
+		w.Write([]byte("\x1b"))
+		time.Sleep(escTimeout / 5)
+		w.Write([]byte("[A"))
+	}()
+	if key, _, err := rr.ReadRune(); err != nil || key != ArrowUp {
+		t.Errorf("got %q %v for a sequence written in pieces, want the up arrow", key, err)
+	}
+
+	if rr.Ready() {
+		t.Errorf("reader should not be ready without input")
+	}
+	w.Write([]byte("\x1b"))
+	rr.Wait()
+	if !rr.Ready() {
+		t.Errorf("reader should be ready after the input is written")
+	}
+	if key, _, err := rr.ReadRune(); err != nil || key != rune(KeyESC) {
+		t.Errorf("got %q %v for a lone esc, want esc", key, err)
+	}
+}
+
+func TestWaitCancel(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	rr := NewRuneReader(r)
+
+	done := make(chan struct{})
+	go func() {
+		rr.Wait()
+		close(done)
+	}()
+	rr.Cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("wait is not cancelled")
+	}
+
+	// the reader is waited for again after a cancelled wait
+	w.Write([]byte("a"))
+	rr.Wait()
+	if key, _, err := rr.ReadRune(); err != nil || key != 'a' {
+		t.Errorf("got %q %v after a cancelled wait, want a", key, err)
+	}
+}
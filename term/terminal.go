@@ -1,13 +1,12 @@
 package term
 
 import (
-	"bufio"
-	"bytes"
+	"errors"
 	"io"
-	"syscall"
-	"unsafe"
+	"strings"
 
 	"github.com/fatih/color"
+	"github.com/mattn/go-runewidth"
 )
 
 var (
@@ -15,12 +14,16 @@ var (
 	reader  Reader
 	writer  Writer
 	colored = true
+
+	altScreen bool // use the alternate screen buffer between Init and Close
 )
 
+// ErrNotTerminal is returned by Init if the input or the output is not a
+// terminal, e.g. if they are redirected to files
+var ErrNotTerminal = errors.New("the input and the output must be a terminal")
+
 type terminalState struct {
-	term   syscall.Termios
-	reader *bufio.Reader
-	buf    *bytes.Buffer
+	mode consoleMode // the settings to be restored on Close
 }
 
 // Writer provides a minimal interface for Stdin.
@@ -39,47 +42,36 @@ type Reader interface {
 type Cell struct {
 	Ch   rune
 	Attr []color.Attribute
+	Fg   *RGB // optional 24-bit foreground color
+	Bg   *RGB // optional 24-bit background color
 }
 
-// Init initializes the term package
-func Init(r Reader, w Writer) error {
-	reader = r
-	writer = w
-	state = newTerminalState(reader)
-	if _, _, err := syscall.Syscall6(syscall.SYS_IOCTL, uintptr(reader.Fd()), ioctlReadTermios, uintptr(unsafe.Pointer(&state.term)), 0, 0, 0); err != 0 {
-		return err
-	}
-
-	newState := state.term
-	// syscall.ECHO | syscall.ECHONL | syscall.ICANON to disable echo
-	// syscall.ISIG is to catch keys like ctr-c or ctrl-d
-	newState.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG
-
-	if _, _, err := syscall.Syscall6(syscall.SYS_IOCTL, uintptr(reader.Fd()), ioctlWriteTermios, uintptr(unsafe.Pointer(&newState)), 0, 0, 0); err != 0 {
-		return err
-	}
-	_, err := writer.Write([]byte(hideCursor))
-	return err
+// Width returns the number of columns that the cell occupies, wide characters
+// like CJK or emoji occupy two columns
+func (c Cell) Width() int {
+	return runewidth.RuneWidth(c.Ch)
 }
 
-// Close restores the terminal state
-func Close() error {
-	if _, _, err := syscall.Syscall6(syscall.SYS_IOCTL, uintptr(reader.Fd()), ioctlWriteTermios, uintptr(unsafe.Pointer(&state.term)), 0, 0, 0); err != 0 {
-		return err
+// Width returns the number of columns that the cells occupy
+func Width(cells []Cell) int {
+	var width int
+	for _, c := range cells {
+		width += c.Width()
 	}
-	_, err := writer.Write([]byte(showCursor))
-	return err
+	return width
 }
 
-func newTerminalState(input Reader) terminalState {
-	buf := new(bytes.Buffer)
-	return terminalState{
-		reader: bufio.NewReader(&BufferedReader{
-			In:     input,
-			Buffer: buf,
-		}),
-		buf: buf,
+// Sprint returns the text of the cells, painted unless the colors are disabled
+func Sprint(cells []Cell) string {
+	var sb strings.Builder
+	for _, c := range cells {
+		if colored {
+			sb.WriteString(c.paint())
+		} else {
+			sb.WriteRune(c.Ch)
+		}
 	}
+	return sb.String()
 }
 
 // Cprint returns the text as colored cell slice
@@ -94,7 +86,57 @@ func Cprint(text string, attrs ...color.Attribute) []Cell {
 	return cells
 }
 
+// EnableAltScreen makes Init switch to the alternate screen buffer and Close
+// restore the main one, so that the previous content of the terminal is kept
+// intact. It must be called before Init.
+func EnableAltScreen() {
+	altScreen = true
+}
+
+// RestoreAltScreen switches to the alternate screen again if it is enabled. It
+// should be called after running a program like less, since it switches back
+// to the main screen on exit.
+func RestoreAltScreen() error {
+	if !altScreen || writer == nil {
+		return nil
+	}
+	_, err := writer.Write([]byte(altScreenOn))
+	return err
+}
+
 // DisableColor makes cell attributes meaningless
 func DisableColor() {
 	colored = false
 }
+
+// ColorEnabled returns false if the cell attributes are not drawn
+func ColorEnabled() bool {
+	return colored
+}
+
+// Highlight returns the text as colored cell slice like Cprint, the runes at
+// the matched byte offsets are underlined as well. The prompt gives the item
+// renderers the offsets in the searched string, which is the item formatted
+// with fmt.Sprint or its search key, so the text should be that string rather
+// than the rendered line with its prefix.
+func Highlight(text string, matches []int, attrs ...color.Attribute) []Cell {
+	return HighlightAttrs(text, matches, attrs, []color.Attribute{color.Underline})
+}
+
+// HighlightAttrs is like Highlight but the matched runes get matchAttrs instead
+// of the underline, in addition to attrs
+func HighlightAttrs(text string, matches []int, attrs, matchAttrs []color.Attribute) []Cell {
+	matched := make(map[int]bool, len(matches))
+	for _, m := range matches {
+		matched[m] = true
+	}
+	cells := make([]Cell, 0, len(text))
+	for i, r := range text {
+		cell := Cell{Ch: r, Attr: attrs}
+		if matched[i] {
+			cell.Attr = append(attrs[:len(attrs):len(attrs)], matchAttrs...)
+		}
+		cells = append(cells, cell)
+	}
+	return cells
+}
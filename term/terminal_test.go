@@ -0,0 +1,31 @@
+package term
+
+import (
+	"testing"
+	"unicode"
+)
+
+func TestHighlight(t *testing.T) {
+	var tests = []struct {
+		text    string
+		matches []int
+		want    string
+	}{
+		{"main.go", []int{0, 5}, "M....G."},
+		{"ファイル.go", []int{13, 14}, ".....GO"},
+		{"日本", []int{3}, ".本"},
+	}
+	for _, test := range tests {
+		var got []rune
+		for _, c := range Highlight(test.text, test.matches) {
+			if len(c.Attr) > 0 {
+				got = append(got, unicode.ToUpper(c.Ch))
+			} else {
+				got = append(got, '.')
+			}
+		}
+		if string(got) != test.want {
+			t.Errorf("input: %s %v\n got: %s want: %s", test.text, test.matches, string(got), test.want)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+//go:build !windows
+// +build !windows
+
+package term
+
+import (
+	"errors"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+type consoleMode = syscall.Termios
+
+// Init initializes the term package
+func Init(r Reader, w Writer) error {
+	reader = r
+	writer = w
+	state = terminalState{}
+	if !IsTerminal(r.Fd()) || !IsTerminal(w.Fd()) {
+		return ErrNotTerminal
+	}
+	if _, _, err := syscall.Syscall6(syscall.SYS_IOCTL, uintptr(reader.Fd()), ioctlReadTermios, uintptr(unsafe.Pointer(&state.mode)), 0, 0, 0); err != 0 {
+		return err
+	}
+
+	newState := state.mode
+	// syscall.ECHO | syscall.ECHONL | syscall.ICANON to disable echo
+	// syscall.ISIG is to catch keys like ctr-c or ctrl-d
+	newState.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG
+
+	if _, _, err := syscall.Syscall6(syscall.SYS_IOCTL, uintptr(reader.Fd()), ioctlWriteTermios, uintptr(unsafe.Pointer(&newState)), 0, 0, 0); err != 0 {
+		return err
+	}
+	if altScreen {
+		if _, err := writer.Write([]byte(altScreenOn)); err != nil {
+			return err
+		}
+	}
+	if _, err := writer.Write([]byte(pasteOn)); err != nil {
+		return err
+	}
+	_, err := writer.Write([]byte(hideCursor))
+	return err
+}
+
+// IsTerminal returns true if the file descriptor is a terminal
+func IsTerminal(fd uintptr) bool {
+	var mode consoleMode
+	_, _, err := syscall.Syscall6(syscall.SYS_IOCTL, fd, ioctlReadTermios, uintptr(unsafe.Pointer(&mode)), 0, 0, 0)
+	return err == 0
+}
+
+// Close restores the terminal state
+func Close() error {
+	if _, _, err := syscall.Syscall6(syscall.SYS_IOCTL, uintptr(reader.Fd()), ioctlWriteTermios, uintptr(unsafe.Pointer(&state.mode)), 0, 0, 0); err != 0 {
+		return err
+	}
+	if _, err := writer.Write([]byte(pasteOff)); err != nil {
+		return err
+	}
+	if altScreen {
+		if _, err := writer.Write([]byte(altScreenOff)); err != nil {
+			return err
+		}
+	}
+	_, err := writer.Write([]byte(showCursor))
+	return err
+}
+
+// Size returns the number of columns and rows of the terminal
+func Size() (int, int, error) {
+	if writer == nil {
+		return 0, 0, errors.New("terminal is not initialized")
+	}
+	var ws struct {
+		Row, Col, Xpixel, Ypixel uint16
+	}
+	if _, _, err := syscall.Syscall(syscall.SYS_IOCTL, writer.Fd(), syscall.TIOCGWINSZ, uintptr(unsafe.Pointer(&ws))); err != 0 {
+		return 0, 0, err
+	}
+	return int(ws.Col), int(ws.Row), nil
+}
+
+// waker interrupts the wait for the input with a pipe that is polled along
+// with the input
+type waker struct {
+	r, w int
+}
+
+func newWaker() (*waker, error) {
+	fds := make([]int, 2)
+	if err := unix.Pipe(fds); err != nil {
+		return nil, err
+	}
+	for _, fd := range fds {
+		unix.CloseOnExec(fd)
+		if err := unix.SetNonblock(fd, true); err != nil {
+			unix.Close(fds[0])
+			unix.Close(fds[1])
+			return nil, err
+		}
+	}
+	return &waker{r: fds[0], w: fds[1]}, nil
+}
+
+// wake interrupts the waits until drain is called
+func (w *waker) wake() {
+	_, _ = unix.Write(w.w, []byte{0})
+}
+
+// drain clears the wake up so that the input can be waited for again
+func (w *waker) drain() {
+	buf := make([]byte, 16)
+	for {
+		if n, err := unix.Read(w.r, buf); n <= 0 || err != nil {
+			return
+		}
+	}
+}
+
+// inputReady returns true if the file descriptor can be read without blocking
+// within the timeout, a negative timeout waits indefinitely. It returns false
+// at once if w is woken up.
+func inputReady(fd uintptr, w *waker, timeout time.Duration) bool {
+	fds := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLIN}}
+	if w != nil {
+		fds = append(fds, unix.PollFd{Fd: int32(w.r), Events: unix.POLLIN})
+	}
+	ms := int(timeout / time.Millisecond)
+	if timeout < 0 {
+		ms = -1
+	}
+	for {
+		_, err := unix.Poll(fds, ms)
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil || (w != nil && fds[1].Revents != 0) {
+			return false
+		}
+		return fds[0].Revents != 0
+	}
+}
@@ -0,0 +1,224 @@
+//go:build windows
+// +build windows
+
+package term
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	kernel32             = windows.NewLazySystemDLL("kernel32.dll")
+	procPeekConsoleInput = kernel32.NewProc("PeekConsoleInputW")
+	procReadConsoleInput = kernel32.NewProc("ReadConsoleInputW")
+)
+
+// the event types of the console input records
+const (
+	keyEvent              = 0x1
+	windowBufferSizeEvent = 0x4
+)
+
+// resized is sent to when the console input has a resize record
+var resized = make(chan struct{}, 1)
+
+// inputRecord is the INPUT_RECORD of the console input
+type inputRecord struct {
+	eventType uint16
+	_         uint16
+	event     [16]byte
+}
+
+// char returns true if the record is a key press that is read as a character,
+// the releases and the modifier keys are skipped by the reads like the focus,
+// the mouse and the resize records
+func (r *inputRecord) char() bool {
+	if r.eventType != keyEvent {
+		return false
+	}
+	down := binary.LittleEndian.Uint32(r.event[0:4])
+	char := binary.LittleEndian.Uint16(r.event[10:12])
+	return down != 0 && char != 0
+}
+
+// consoleMode holds the modes of the input and output console handles
+type consoleMode struct {
+	in, out uint32
+}
+
+// Init initializes the term package
+func Init(r Reader, w Writer) error {
+	reader = r
+	writer = w
+	state = terminalState{}
+	if !IsTerminal(r.Fd()) || !IsTerminal(w.Fd()) {
+		return ErrNotTerminal
+	}
+	in, out := windows.Handle(reader.Fd()), windows.Handle(writer.Fd())
+	if err := windows.GetConsoleMode(in, &state.mode.in); err != nil {
+		return err
+	}
+	if err := windows.GetConsoleMode(out, &state.mode.out); err != nil {
+		return err
+	}
+
+	// disable echo and line buffering, ctrl-c is read as a key like on unix.
+	// the virtual terminal input sends the arrow keys as escape sequences so
+	// that the rune reader can parse them, and the window input the resizes.
+	inMode := state.mode.in &^ (windows.ENABLE_ECHO_INPUT | windows.ENABLE_LINE_INPUT | windows.ENABLE_PROCESSED_INPUT)
+	inMode |= windows.ENABLE_VIRTUAL_TERMINAL_INPUT | windows.ENABLE_WINDOW_INPUT
+	if err := windows.SetConsoleMode(in, inMode); err != nil {
+		return err
+	}
+	// the writer uses ANSI escape codes to draw
+	outMode := state.mode.out | windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING
+	if err := windows.SetConsoleMode(out, outMode); err != nil {
+		return err
+	}
+	if altScreen {
+		if _, err := writer.Write([]byte(altScreenOn)); err != nil {
+			return err
+		}
+	}
+	if _, err := writer.Write([]byte(pasteOn)); err != nil {
+		return err
+	}
+	_, err := writer.Write([]byte(hideCursor))
+	return err
+}
+
+// Resized returns a channel that is sent to when the console is resized, the
+// resizes are read from the console input while the input is waited for
+func Resized() <-chan struct{} {
+	return resized
+}
+
+func notifyResized() {
+	select {
+	case resized <- struct{}{}:
+	default: // a resize is already pending
+	}
+}
+
+// IsTerminal returns true if the handle is a console
+func IsTerminal(fd uintptr) bool {
+	var mode uint32
+	return windows.GetConsoleMode(windows.Handle(fd), &mode) == nil
+}
+
+// Close restores the terminal state
+func Close() error {
+	if err := windows.SetConsoleMode(windows.Handle(reader.Fd()), state.mode.in); err != nil {
+		return err
+	}
+	if _, err := writer.Write([]byte(pasteOff)); err != nil {
+		return err
+	}
+	if altScreen {
+		if _, err := writer.Write([]byte(altScreenOff)); err != nil {
+			return err
+		}
+	}
+	if _, err := writer.Write([]byte(showCursor)); err != nil {
+		return err
+	}
+	return windows.SetConsoleMode(windows.Handle(writer.Fd()), state.mode.out)
+}
+
+// Size returns the number of columns and rows of the visible console window
+func Size() (int, int, error) {
+	if writer == nil {
+		return 0, 0, errors.New("terminal is not initialized")
+	}
+	var info windows.ConsoleScreenBufferInfo
+	if err := windows.GetConsoleScreenBufferInfo(windows.Handle(writer.Fd()), &info); err != nil {
+		return 0, 0, err
+	}
+	width := int(info.Window.Right-info.Window.Left) + 1
+	height := int(info.Window.Bottom-info.Window.Top) + 1
+	return width, height, nil
+}
+
+// waker interrupts the wait for the input with an event that is waited for
+// along with the input
+type waker struct {
+	event windows.Handle
+}
+
+func newWaker() (*waker, error) {
+	event, err := windows.CreateEvent(nil, 1, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &waker{event: event}, nil
+}
+
+// wake interrupts the waits until drain is called
+func (w *waker) wake() {
+	_ = windows.SetEvent(w.event)
+}
+
+// drain clears the wake up so that the input can be waited for again
+func (w *waker) drain() {
+	_ = windows.ResetEvent(w.event)
+}
+
+// inputReady returns true if the console has a character to read within the
+// timeout, a negative timeout waits indefinitely. The console is signaled for
+// the other records as well, they are consumed so that a read doesn't block
+// on them. It returns false at once if w is woken up.
+func inputReady(fd uintptr, w *waker, timeout time.Duration) bool {
+	in := windows.Handle(fd)
+	handles := []windows.Handle{in}
+	if w != nil {
+		handles = append(handles, w.event)
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		ms := uint32(windows.INFINITE)
+		if timeout >= 0 {
+			left := time.Until(deadline)
+			if left < 0 {
+				left = 0
+			}
+			ms = uint32(left / time.Millisecond)
+		}
+		event, err := windows.WaitForMultipleObjects(handles, false, ms)
+		if err != nil || event != windows.WAIT_OBJECT_0 {
+			return false // timed out or woken up
+		}
+		if pendingChar(in) {
+			return true
+		}
+	}
+}
+
+// pendingChar returns true if the console input has a character to read, the
+// records are consumed if none of them is one and the resizes are notified
+func pendingChar(in windows.Handle) bool {
+	records := make([]inputRecord, 16)
+	var n uint32
+	r, _, _ := procPeekConsoleInput.Call(uintptr(in), uintptr(unsafe.Pointer(&records[0])), uintptr(len(records)), uintptr(unsafe.Pointer(&n)))
+	if r == 0 {
+		return true // the read tells what is wrong
+	}
+	var char bool
+	for _, record := range records[:n] {
+		if record.eventType == windowBufferSizeEvent {
+			notifyResized()
+		}
+		char = char || record.char()
+	}
+	if char {
+		return true
+	}
+	if n > 0 {
+		procReadConsoleInput.Call(uintptr(in), uintptr(unsafe.Pointer(&records[0])), uintptr(n), uintptr(unsafe.Pointer(&n)))
+	}
+	return false
+}